@@ -0,0 +1,84 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Bedrock Edition stores NBT with little-endian numbers and UTF-8 strings (level.dat, .mcstructure files, world storage).
+
+// ReadBedrockNamedTag reads a little-endian named tag. See ReadNamedTag for more info.
+func ReadBedrockNamedTag(r io.Reader) (Tag, string, error) {
+	d := newDecoder(r)
+	d.SetByteOrder(binary.LittleEndian)
+	d.SetStringEncoding(UTF8)
+	return d.Decode()
+}
+
+// WriteBedrockNamedTag writes a little-endian named tag. See WriteNamedTag for more info.
+func WriteBedrockNamedTag(w io.Writer, name string, tag Tag) error {
+	e := NewEncoder(w)
+	e.SetByteOrder(binary.LittleEndian)
+	e.SetStringEncoding(UTF8)
+	return e.Encode(name, tag)
+}
+
+// ReadBedrockLevelDat reads a Bedrock Edition level.dat file. These files start with an 8 byte header:
+// the storage version and the length of the following NBT data, both as little-endian int32.
+func ReadBedrockLevelDat(r io.Reader) (version int32, tag Tag, name string, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	version = int32(binary.LittleEndian.Uint32(header[0:4]))
+	length := int32(binary.LittleEndian.Uint32(header[4:8]))
+	if length < 0 {
+		err = errors.New("level.dat has negative length?")
+		return
+	}
+
+	lr := &io.LimitedReader{R: r, N: int64(length)}
+	if tag, name, err = ReadBedrockNamedTag(lr); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if lr.N != 0 {
+		err = errors.New("level.dat length does not match its data")
+	}
+	return
+}
+
+// WriteBedrockLevelDat writes a Bedrock Edition level.dat file with the given storage version. See ReadBedrockLevelDat.
+func WriteBedrockLevelDat(w io.Writer, version int32, name string, tag Tag) error {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 8))
+	if err := WriteBedrockNamedTag(buf, name, tag); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint32(data[0:4], uint32(version))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	_, err := w.Write(data)
+	return err
+}
+
+// ReadBedrockNetworkNamedTag reads a named tag in the network format of Bedrock Edition (see Decoder.UseNetworkFormat),
+// enforcing NetworkLimits. See ReadNamedTag for more info.
+func ReadBedrockNetworkNamedTag(r io.Reader) (Tag, string, error) {
+	d := newDecoder(r)
+	d.UseNetworkFormat()
+	d.SetLimits(NetworkLimits)
+	return d.Decode()
+}
+
+// WriteBedrockNetworkNamedTag writes a named tag in the network format of Bedrock Edition. See WriteNamedTag for more info.
+func WriteBedrockNetworkNamedTag(w io.Writer, name string, tag Tag) error {
+	e := NewEncoder(w)
+	e.UseNetworkFormat()
+	return e.Encode(name, tag)
+}
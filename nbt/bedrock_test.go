@@ -0,0 +1,197 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// bedrockTestData is a little-endian compound "" with a list "L" of the shorts 0x0201 and -2.
+var bedrockTestData = []byte{
+	0x0a, 0x00, 0x00,
+	0x09, 0x01, 0x00, 'L', 0x02, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02, 0xfe, 0xff,
+	0x00,
+}
+
+func TestBedrockRoundtrip(t *testing.T) {
+	tag, name, err := ReadBedrockNamedTag(bytes.NewReader(bedrockTestData))
+	if err != nil {
+		t.Fatalf("Could not read NBT data: %s", err)
+	}
+	if name != "" {
+		t.Errorf("Wrong name %#v", name)
+	}
+
+	l, err := tag.Payload.(TagCompound).GetList("L")
+	if err != nil {
+		t.Fatalf("Could not get L: %s", err)
+	}
+	if l.Type != TAG_Short || len(l.Elems) != 2 || l.Elems[0].(int16) != 0x0201 || l.Elems[1].(int16) != -2 {
+		t.Errorf("Wrong list: %s", Tag{TAG_List, l})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteBedrockNamedTag(buf, name, tag); err != nil {
+		t.Fatalf("Could not write NBT data: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), bedrockTestData) {
+		t.Errorf("Roundtrip changed data.\nWant: % x\nHave: % x", bedrockTestData, buf.Bytes())
+	}
+}
+
+func TestBedrockScalars(t *testing.T) {
+	tests := []struct {
+		tag  Tag
+		want []byte
+	}{
+		{NewIntTag(0x01020304), []byte{0x04, 0x03, 0x02, 0x01}},
+		{NewLongTag(-2), []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{NewFloatTag(1), []byte{0x00, 0x00, 0x80, 0x3f}},
+		{NewDoubleTag(1), []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f}},
+		{NewIntArrayTag([]int32{1}), []byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}},
+		{NewStringTag("ab"), []byte{0x02, 0x00, 'a', 'b'}},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := WriteBedrockNamedTag(buf, "", test.tag); err != nil {
+			t.Fatalf("Could not write %s: %s", test.tag, err)
+		}
+		want := append([]byte{byte(test.tag.Type), 0x00, 0x00}, test.want...)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("Wrong encoding of %s.\nWant: % x\nHave: % x", test.tag, want, buf.Bytes())
+		}
+
+		tag, _, err := ReadBedrockNamedTag(buf)
+		if err != nil {
+			t.Fatalf("Could not read %s: %s", test.tag, err)
+		}
+		if tag.String() != test.tag.String() {
+			t.Errorf("Roundtrip changed %s to %s", test.tag, tag)
+		}
+	}
+}
+
+func TestBedrockLevelDat(t *testing.T) {
+	data := append([]byte{0x0a, 0x00, 0x00, 0x00, byte(len(bedrockTestData)), 0x00, 0x00, 0x00}, bedrockTestData...)
+
+	version, tag, name, err := ReadBedrockLevelDat(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Could not read level.dat: %s", err)
+	}
+	if version != 10 {
+		t.Errorf("Wrong version %d", version)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteBedrockLevelDat(buf, version, name, tag); err != nil {
+		t.Fatalf("Could not write level.dat: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Roundtrip changed data.\nWant: % x\nHave: % x", data, buf.Bytes())
+	}
+
+	bad := append([]byte{}, data...)
+	bad[4]++
+	if _, _, _, err := ReadBedrockLevelDat(bytes.NewReader(bad)); err == nil {
+		t.Errorf("Wrong length in header should fail")
+	}
+}
+
+func TestBedrockNetworkScalars(t *testing.T) {
+	tests := []struct {
+		tag  Tag
+		want []byte
+	}{
+		{NewIntTag(0), []byte{0x00}},
+		{NewIntTag(-1), []byte{0x01}},
+		{NewIntTag(1), []byte{0x02}},
+		{NewIntTag(300), []byte{0xd8, 0x04}},
+		{NewIntTag(-2147483648), []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+		{NewLongTag(-9223372036854775808), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{NewShortTag(0x0102), []byte{0x02, 0x01}},
+		{NewFloatTag(1), []byte{0x00, 0x00, 0x80, 0x3f}},
+		{NewStringTag("ab"), []byte{0x02, 'a', 'b'}},
+		{NewByteArrayTag([]byte{7}), []byte{0x02, 0x07}},
+		{NewIntArrayTag([]int32{-1, 1}), []byte{0x04, 0x01, 0x02}},
+		{NewListTag(TAG_Long, []int64{2}), []byte{0x04, 0x02, 0x04}},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := WriteBedrockNetworkNamedTag(buf, "n", test.tag); err != nil {
+			t.Fatalf("Could not write %s: %s", test.tag, err)
+		}
+		want := append([]byte{byte(test.tag.Type), 0x01, 'n'}, test.want...)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("Wrong encoding of %s.\nWant: % x\nHave: % x", test.tag, want, buf.Bytes())
+		}
+
+		tag, name, err := ReadBedrockNetworkNamedTag(buf)
+		if err != nil {
+			t.Fatalf("Could not read %s: %s", test.tag, err)
+		}
+		if name != "n" || tag.String() != test.tag.String() {
+			t.Errorf("Roundtrip changed %s to %#v %s", test.tag, name, tag)
+		}
+	}
+}
+
+func TestBedrockNetworkLimits(t *testing.T) {
+	// 600 nested lists
+	var data []byte
+	data = append(data, TAG_List, 0x00)
+	for i := 0; i < 600; i++ {
+		data = append(data, TAG_List, 0x02)
+	}
+	if _, _, err := ReadBedrockNetworkNamedTag(bytes.NewReader(data)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Deep nesting: want ErrLimitExceeded, have %v", err)
+	}
+
+	// A byte array claiming to be 1 GiB large.
+	data = []byte{TAG_Byte_Array, 0x00, 0x80, 0x80, 0x80, 0x80, 0x08}
+	if _, _, err := ReadBedrockNetworkNamedTag(bytes.NewReader(data)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Huge array: want ErrLimitExceeded, have %v", err)
+	}
+
+	// A list with more elements than allowed, within MaxBytes.
+	data = []byte{TAG_List, 0x00, TAG_Byte, 0x82, 0x80, 0x40} // 512 Ki + 1 elements
+	data = append(data, make([]byte, 1024*1024)...)
+	if _, _, err := ReadBedrockNetworkNamedTag(bytes.NewReader(data)); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Long list: want ErrLimitExceeded, have %v", err)
+	}
+
+	// Overlong varint
+	data = []byte{TAG_Int, 0x00, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}
+	if _, _, err := ReadBedrockNetworkNamedTag(bytes.NewReader(data)); err == nil {
+		t.Errorf("Overlong varint should fail")
+	}
+}
+
+func TestBedrockNetworkLongString(t *testing.T) {
+	tag := NewStringTag(strings.Repeat("a", 40000))
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.UseNetworkFormat()
+	if err := e.Encode("s", tag); err != nil {
+		t.Fatalf("Could not encode: %s", err)
+	}
+	d := NewDecoder(bytes.NewReader(buf.Bytes()))
+	d.UseNetworkFormat()
+	if have, _, err := d.Decode(); err != nil || !Equal(have, tag) {
+		t.Errorf("Roundtrip failed: %v", err)
+	}
+
+	// NetworkLimits still restrict strings to the length Java Edition can store.
+	if _, _, err := ReadBedrockNetworkNamedTag(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("NetworkLimits: want ErrLimitExceeded, have %v", err)
+	}
+
+	// The length of the Java format is 16 bits.
+	if err := NewEncoder(io.Discard).Encode("s", tag); err == nil {
+		t.Errorf("Java format should not encode a string of 40000 bytes")
+	}
+}
@@ -0,0 +1,160 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/silvasur/kagus"
+)
+
+// This is the original decoder, which reads every value with binary.Read. It is only kept as a baseline for the
+// decoding benchmarks.
+
+func binaryReadTagData(r io.Reader, tt TagType) (interface{}, error) {
+	switch tt {
+	case TAG_End:
+	case TAG_Byte:
+		var v uint8
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Short:
+		var v int16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Int:
+		var v int32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Long:
+		var v int64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Float:
+		var v float32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Double:
+		var v float64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case TAG_Byte_Array:
+		var l int32
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return nil, err
+		}
+		if l < 0 {
+			return nil, errors.New("Byte array has negative length?")
+		}
+
+		data := make([]byte, l)
+		_, err := io.ReadFull(r, data)
+		return data, err
+	case TAG_String:
+		var l int16
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return nil, err
+		}
+		if l < 0 {
+			return nil, errors.New("String has negative length?")
+		}
+
+		data := make([]byte, l)
+		_, err := io.ReadFull(r, data)
+		return string(data), err
+	case TAG_List:
+		_ltt, err := kagus.ReadByte(r)
+		if err != nil {
+			return nil, err
+		}
+		ltt := TagType(_ltt)
+
+		var l int32
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return nil, err
+		}
+		if l < 0 {
+			return nil, errors.New("List has negative length?")
+		}
+
+		tl := TagList{Type: ltt, Elems: make([]interface{}, l)}
+		for i := 0; i < int(l); i++ {
+			if tl.Elems[i], err = binaryReadTagData(r, ltt); err != nil {
+				return nil, err
+			}
+		}
+		return tl, nil
+	case TAG_Compound:
+		comp := make(TagCompound)
+		for {
+			tag, name, err := binaryReadNamedTag(r)
+			if err != nil {
+				return nil, err
+			}
+			if tag.Type == TAG_End {
+				break
+			}
+			comp[name] = tag
+		}
+		return comp, nil
+	case TAG_Int_Array:
+		var l int32
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return nil, err
+		}
+		if l < 0 {
+			return nil, errors.New("Int Array has negative length?")
+		}
+
+		data := make([]int32, l)
+		for i := 0; i < int(l); i++ {
+			var e int32
+			if err := binary.Read(r, binary.BigEndian, &e); err != nil {
+				return nil, err
+			}
+			data[i] = e
+		}
+		return data, nil
+	case TAG_Long_Array:
+		var l int32
+		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+			return nil, err
+		}
+		if l < 0 {
+			return nil, errors.New("Long Array has negative length?")
+		}
+
+		data := make([]int64, l)
+		for i := 0; i < int(l); i++ {
+			var e int64
+			if err := binary.Read(r, binary.BigEndian, &e); err != nil {
+				return nil, err
+			}
+			data[i] = e
+		}
+		return data, nil
+	}
+
+	return nil, errors.New("Unknown tag type")
+}
+
+// binaryReadNamedTag is ReadNamedTag of the original decoder.
+func binaryReadNamedTag(r io.Reader) (Tag, string, error) {
+	_tt, err := kagus.ReadByte(r)
+	if err != nil {
+		return Tag{}, "", err
+	}
+	tt := TagType(_tt)
+
+	if tt == TAG_End {
+		return Tag{Type: tt}, "", nil
+	}
+
+	name, err := binaryReadTagData(r, TAG_String)
+	if err != nil {
+		return Tag{}, "", err
+	}
+
+	td, err := binaryReadTagData(r, tt)
+	return Tag{Type: tt, Payload: td}, name.(string), err
+}
@@ -0,0 +1,378 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func uncompressedBigtest(t testing.TB) []byte {
+	r, err := gzip.NewReader(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// chunkTestData returns a tag similar to a chunk: lots of small compounds, strings and long arrays.
+func chunkTestData(t testing.TB) []byte {
+	var sections []interface{}
+	for y := 0; y < 24; y++ {
+		states := make([]int64, 256)
+		for i := range states {
+			states[i] = int64(i*y) * 0x0123456789
+		}
+		var palette []interface{}
+		for i := 0; i < 16; i++ {
+			palette = append(palette, TagCompound{
+				"Name":       NewStringTag("minecraft:stone"),
+				"Properties": Tag{TAG_Compound, TagCompound{"axis": NewStringTag("y")}},
+			})
+		}
+		sections = append(sections, TagCompound{
+			"Y":          NewByteTag(byte(y)),
+			"BlockLight": NewByteArrayTag(make([]byte, 2048)),
+			"block_states": Tag{TAG_Compound, TagCompound{
+				"palette": Tag{TAG_List, TagList{TAG_Compound, palette}},
+				"data":    NewLongArrayTag(states),
+			}},
+		})
+	}
+	chunk := TagCompound{
+		"xPos":       NewIntTag(3),
+		"zPos":       NewIntTag(-7),
+		"Status":     NewStringTag("minecraft:full"),
+		"LastUpdate": NewLongTag(123456789),
+		"sections":   Tag{TAG_List, TagList{TAG_Compound, sections}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteNamedTag(buf, "", Tag{TAG_Compound, chunk}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseNamedTag(t *testing.T) {
+	for _, data := range [][]byte{uncompressedBigtest(t), chunkTestData(t)} {
+		want, wantName, err := ReadNamedTag(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Could not read tag: %s", err)
+		}
+
+		have, name, err := ParseNamedTag(data)
+		if err != nil || name != wantName || !Equal(have, want) {
+			t.Errorf("ParseNamedTag: Tag differs from ReadNamedTag (name %q, err %v)", name, err)
+		}
+
+		d := NewBytesDecoder(data)
+		d.AliasInput()
+		have, name, err = d.Decode()
+		if err != nil || name != wantName || !Equal(have, want) {
+			t.Errorf("AliasInput: Tag differs from ReadNamedTag (name %q, err %v)", name, err)
+		}
+	}
+}
+
+func TestBytesDecoderAlias(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "", Tag{TAG_Compound, TagCompound{
+		"data":  NewByteArrayTag([]byte{1, 2, 3}),
+		"ascii": NewStringTag("abc"),
+		"text":  NewStringTag("ä\x00"),
+	}})
+	data := buf.Bytes()
+
+	for _, alias := range []bool{false, true} {
+		input := append([]byte(nil), data...)
+		d := NewBytesDecoder(input)
+		if alias {
+			d.AliasInput()
+		}
+		tag, _, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Could not decode: %s", err)
+		}
+		for i := range input {
+			input[i] = 0xff
+		}
+
+		comp := tag.Payload.(TagCompound)
+		if shared := comp["data"].Payload.([]byte)[0] == 0xff; shared != alias {
+			t.Errorf("alias=%t: Byte array shared with input: %t", alias, shared)
+		}
+		if shared := comp["ascii"].Payload.(string) != "abc"; shared != alias {
+			t.Errorf("alias=%t: String shared with input: %t", alias, shared)
+		}
+		if s := comp["text"].Payload.(string); s != "ä\x00" {
+			t.Errorf("alias=%t: Wrong string %q", alias, s)
+		}
+	}
+}
+
+func TestBytesDecoderStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "a", NewIntTag(1))
+	n := int64(buf.Len())
+	WriteNamedTag(buf, "b", NewStringTag("x"))
+
+	d := NewBytesDecoder(buf.Bytes())
+	if _, name, err := d.Decode(); err != nil || name != "a" || d.InputOffset() != n {
+		t.Errorf("First tag: name %q, offset %d, err %v", name, d.InputOffset(), err)
+	}
+	if _, name, err := d.Decode(); err != nil || name != "b" || d.InputOffset() != int64(buf.Len()) {
+		t.Errorf("Second tag: name %q, offset %d, err %v", name, d.InputOffset(), err)
+	}
+	if _, _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Want io.EOF, have %v", err)
+	}
+
+	zbuf := new(bytes.Buffer)
+	WriteCompressedNamedTag(zbuf, Zlib, "z", NewIntTag(2))
+	d = NewBytesDecoder(zbuf.Bytes())
+	d.SetCompression(Zlib)
+	if tag, name, err := d.Decode(); err != nil || name != "z" || !Equal(tag, NewIntTag(2)) {
+		t.Errorf("Compressed: tag %v, name %q, err %v", tag, name, err)
+	}
+}
+
+func TestBytesDecoderErrors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "Level", Tag{TAG_Compound, TagCompound{"x": NewLongTag(1), "s": NewStringTag("abc")}})
+	data := buf.Bytes()
+
+	inputs := map[string][]byte{
+		"Int array":  {TAG_Int_Array, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff},
+		"Byte array": {TAG_Byte_Array, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff},
+		"String":     {TAG_String, 0x00, 0x00, 0x7f, 0xff},
+		"List":       {TAG_List, 0x00, 0x00, TAG_Int, 0x7f, 0xff, 0xff, 0xff},
+	}
+	for i := 1; i < len(data); i++ {
+		inputs[fmt.Sprintf("Truncated at %d", i)] = data[:i]
+	}
+	for what, input := range inputs {
+		if _, _, err := ParseNamedTag(input); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: Want io.ErrUnexpectedEOF, have %v", what, err)
+		}
+	}
+
+	d := NewBytesDecoder(data)
+	d.SetLimits(Limits{MaxBytes: int64(len(data) - 1)})
+	if _, _, err := d.Decode(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Want ErrLimitExceeded, have %v", err)
+	}
+
+	_, _, err := ParseNamedTag([]byte{TAG_Compound, 0x00, 0x00, 0x2a, 0x00, 0x01, 'x'})
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "x" || de.Type != 0x2a || de.Offset != 7 {
+		t.Errorf("Unknown tag type: wrong error %v", err)
+	}
+}
+
+func benchmarkDecode(b *testing.B, data []byte) {
+	// The original decoder as a baseline.
+	want, _, _ := ParseNamedTag(data)
+	if have, _, err := binaryReadNamedTag(bytes.NewReader(data)); err != nil || !Equal(have, want) {
+		b.Fatalf("BinaryRead decoded a different tag (err %v)", err)
+	}
+	b.Run("BinaryRead", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, _, err := binaryReadNamedTag(bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Reader", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, _, err := NewDecoder(bytes.NewReader(data)).Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, _, err := ParseNamedTag(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("BytesAlias", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			d := NewBytesDecoder(data)
+			d.AliasInput()
+			if _, _, err := d.Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeBigtest(b *testing.B) { benchmarkDecode(b, uncompressedBigtest(b)) }
+
+func BenchmarkDecodeChunk(b *testing.B) { benchmarkDecode(b, chunkTestData(b)) }
+
+// writeCounter counts the calls to Write.
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func appendTestTag() Tag {
+	return Tag{TAG_Compound, TagCompound{
+		"byte":   NewByteTag(0xfe),
+		"short":  NewShortTag(-2),
+		"int":    NewIntTag(-3),
+		"long":   NewLongTag(-4),
+		"float":  NewFloatTag(0.5),
+		"double": NewDoubleTag(-0.25),
+		"bytes":  NewByteArrayTag([]byte{1, 2, 3}),
+		"string": NewStringTag("ä\x00😀"),
+		"list":   NewListTag(TAG_String, []interface{}{"a", "b"}),
+		"ints":   NewIntArrayTag([]int32{1, -2, 1 << 30}),
+		"longs":  NewLongArrayTag([]int64{1, -2, 1 << 60}),
+		"nested": Tag{TAG_Compound, TagCompound{"x": NewIntTag(1)}},
+	}}
+}
+
+func TestAppendEncode(t *testing.T) {
+	tag := appendTestTag()
+	setups := map[string]func(e *Encoder){
+		"Java":          func(e *Encoder) {},
+		"Little-endian": func(e *Encoder) { e.SetByteOrder(binary.LittleEndian) },
+		"Network":       func(e *Encoder) { e.UseNetworkFormat() },
+		"Network MUTF8": func(e *Encoder) { e.UseNetworkFormat(); e.SetStringEncoding(ModifiedUTF8) },
+	}
+	for what, setup := range setups {
+		w := new(writeCounter)
+		e := NewEncoder(w)
+		e.SetSortKeys(true)
+		setup(e)
+		for i := 0; i < 2; i++ {
+			if err := e.Encode("root", tag); err != nil {
+				t.Fatalf("%s: Could not encode: %s", what, err)
+			}
+		}
+		if w.writes != 2 {
+			t.Errorf("%s: Want 1 write per root tag, have %d", what, w.writes)
+		}
+		want := w.Bytes()
+
+		prefix := []byte("prefix")
+		e = NewEncoder(nil)
+		e.SetSortKeys(true)
+		setup(e)
+		have, err := e.AppendEncode(prefix, "root", tag)
+		if err == nil {
+			have, err = e.AppendEncode(have, "root", tag)
+		}
+		if err != nil || !bytes.Equal(have, append(prefix, want...)) {
+			t.Errorf("%s: AppendEncode differs from Encode (err %v)", what, err)
+		}
+	}
+
+	// Without sorted keys, the order of the entries is random.
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "root", tag)
+	if have, err := AppendNamedTag(nil, "root", tag); err != nil || len(have) != buf.Len() {
+		t.Errorf("AppendNamedTag differs from WriteNamedTag (err %v)", err)
+	} else if back, _, err := ParseNamedTag(have); err != nil || !Equal(back, tag) {
+		t.Errorf("AppendNamedTag wrote a different tag: %v, %v", back, err)
+	}
+	if have, name, err := ParseNamedTag(buf.Bytes()); err != nil || name != "root" || !Equal(have, tag) {
+		t.Errorf("Tag changed: %v, %q, %v", have, name, err)
+	}
+}
+
+func TestAppendEncodeInvalid(t *testing.T) {
+	invalid := Tag{TAG_Compound, TagCompound{"list": NewListTag(TAG_String, []interface{}{"a", 3})}}
+
+	dst := []byte("prefix")
+	if have, err := AppendNamedTag(dst, "", invalid); err == nil || !bytes.Equal(have, dst) {
+		t.Errorf("Want dst and an error, have %q, %v", have, err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteNamedTag(buf, "", invalid); err == nil || buf.Len() != 0 {
+		t.Errorf("Want nothing written and an error, have %d bytes, %v", buf.Len(), err)
+	}
+}
+
+func TestAppendNamedTagDoesNotAllocate(t *testing.T) {
+	tag, _, err := ParseNamedTag(chunkTestData(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := AppendNamedTag(nil, "", tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		buf, err = AppendNamedTag(buf[:0], "", tag)
+	})
+	if err != nil || allocs > 0 {
+		t.Errorf("Want no allocations, have %v (err %v)", allocs, err)
+	}
+}
+
+func benchmarkEncode(b *testing.B, data []byte) {
+	tag, _, err := ParseNamedTag(data)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Encoder", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		e := NewEncoder(io.Discard)
+		for i := 0; i < b.N; i++ {
+			if err := e.Encode("", tag); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("WriteNamedTag", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if err := WriteNamedTag(io.Discard, "", tag); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("AppendNamedTag", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			if buf, err = AppendNamedTag(buf[:0], "", tag); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkEncodeBigtest(b *testing.B) { benchmarkEncode(b, uncompressedBigtest(b)) }
+
+func BenchmarkEncodeChunk(b *testing.B) { benchmarkEncode(b, chunkTestData(b)) }
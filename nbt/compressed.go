@@ -1,54 +1,200 @@
 package nbt
 
 import (
+	"bufio"
 	"compress/gzip"
 	"compress/zlib"
 	"io"
+	"sync"
 )
 
 // Some helpers for reading / writing compressed NBT data, since NBT data is often compressed.
 
-// ReadGzipdNamedTag reads a gzip compressed named tag. See ReadNamedTags for more info.
-func ReadGzipdNamedTag(r io.Reader) (Tag, string, error) {
-	decomp, err := gzip.NewReader(r)
-	if err != nil {
-		return Tag{}, "", err
+// Compression is a compression method for a NBT stream.
+//
+// NoCompression, Gzip, Zlib and LZ4 are predefined; other methods can be added with RegisterCompression.
+type Compression interface {
+	// ID returns the id of the compression in region chunk headers, or CustomCompressionID.
+	ID() byte
+
+	// String returns the name of the compression. For custom compressions, it is the namespaced id stored in region
+	// files (e.g. "example:zstd").
+	String() string
+
+	// NewReader returns a reader that decompresses the data read from r.
+	NewReader(r io.Reader) (io.Reader, error)
+
+	// NewWriter returns a writer that compresses data and writes it to w. Closing it must finish the compressed stream,
+	// but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Compression ids used in region chunk headers.
+const (
+	GzipID              byte = 1
+	ZlibID              byte = 2
+	NoCompressionID     byte = 3
+	LZ4ID               byte = 4 // Since Java Edition 1.20.5.
+	CustomCompressionID byte = 127
+)
+
+// Predefined compressions. Gzip and Zlib use the default compression level, see GzipLevel and ZlibLevel for others.
+var (
+	NoCompression Compression = noCompression{}
+	Gzip          Compression = gzipCompression{gzip.DefaultCompression}
+	Zlib          Compression = zlibCompression{zlib.DefaultCompression}
+	LZ4           Compression = lz4Compression{}
+)
+
+// GzipLevel returns a gzip compression with a level as in compress/gzip.
+func GzipLevel(level int) Compression { return gzipCompression{level} }
+
+// ZlibLevel returns a zlib compression with a level as in compress/zlib.
+func ZlibLevel(level int) Compression { return zlibCompression{level} }
+
+type noCompression struct{}
+
+func (noCompression) ID() byte                                 { return NoCompressionID }
+func (noCompression) String() string                           { return "none" }
+func (noCompression) NewReader(r io.Reader) (io.Reader, error) { return r, nil }
+func (noCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+type gzipCompression struct{ level int }
+
+func (gzipCompression) ID() byte                                 { return GzipID }
+func (gzipCompression) String() string                           { return "gzip" }
+func (gzipCompression) NewReader(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+func (c gzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.level)
+}
+
+type zlibCompression struct{ level int }
+
+func (zlibCompression) ID() byte                                 { return ZlibID }
+func (zlibCompression) String() string                           { return "zlib" }
+func (zlibCompression) NewReader(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }
+func (c zlibCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, c.level)
+}
+
+var (
+	registryMu  sync.RWMutex
+	compByID    = map[byte]Compression{GzipID: Gzip, ZlibID: Zlib, NoCompressionID: NoCompression, LZ4ID: LZ4}
+	compsByName = map[string]Compression{}
+)
+
+// RegisterCompression makes c available to CompressionByID (or CustomCompressionByName, if its ID is
+// CustomCompressionID), replacing a previously registered compression with the same id or name. It is usually called
+// from an init function.
+func RegisterCompression(c Compression) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if c.ID() == CustomCompressionID {
+		compsByName[c.String()] = c
+	} else {
+		compByID[c.ID()] = c
 	}
+}
+
+// CompressionByID returns the registered compression with a region chunk id.
+func CompressionByID(id byte) (Compression, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := compByID[id]
+	return c, ok
+}
 
-	return ReadNamedTag(decomp)
+// CustomCompressionByName returns the registered custom compression with a namespaced id.
+func CustomCompressionByName(name string) (Compression, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := compsByName[name]
+	return c, ok
+}
+
+// isUncompressed checks, if c (which may be nil) does not compress.
+func isUncompressed(c Compression) bool {
+	return c == nil || c.ID() == NoCompressionID
+}
+
+func readCompressedNamedTag(r io.Reader, c Compression) (Tag, string, error) {
+	d := NewDecoder(r)
+	d.SetCompression(c)
+	return d.Decode()
+}
+
+// WriteCompressedNamedTag writes a named tag with compression c. Together with ReadAnyNamedTag, it can be used to
+// write a file back with its original compression.
+func WriteCompressedNamedTag(w io.Writer, c Compression, name string, tag Tag) error {
+	e := NewEncoder(w)
+	e.SetCompression(c)
+	if err := e.Encode(name, tag); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// ReadGzipdNamedTag reads a gzip compressed named tag. See ReadNamedTags for more info.
+func ReadGzipdNamedTag(r io.Reader) (Tag, string, error) {
+	return readCompressedNamedTag(r, Gzip)
 }
 
 // WriteGzipdNamedTag writes a gzip compressed named tag. See WriteNamedTag for more info.
-func WriteGzipdNamedTag(w io.Writer, name string, tag Tag) (outerr error) {
-	comp := gzip.NewWriter(w)
-	defer func(){
-		err := comp.Close()
-		if outerr != nil {
-			outerr = err
-		}
-	}()
-	outerr = WriteNamedTag(comp, name, tag)
-	return
+func WriteGzipdNamedTag(w io.Writer, name string, tag Tag) error {
+	return WriteCompressedNamedTag(w, Gzip, name, tag)
 }
 
 // ReadZlibdNamedTag reads a zlib compressed named tag. See ReadNamedTags for more info.
 func ReadZlibdNamedTag(r io.Reader) (Tag, string, error) {
-	decomp, err := zlib.NewReader(r)
-	if err != nil {
-		return Tag{}, "", err
-	}
-
-	return ReadNamedTag(decomp)
+	return readCompressedNamedTag(r, Zlib)
 }
 
 // WriteZlibdNamedTag writes a zlib compressed named tag. See WriteNamedTag for more info.
-func WriteZlibdNamedTag(w io.Writer, name string, tag Tag) (outerr error) {
-	comp := zlib.NewWriter(w)
-	defer func(){
-		err := comp.Close()
-		if outerr != nil {
-			outerr = err
-		}
-	}()
-	return WriteNamedTag(comp, name, tag)
+func WriteZlibdNamedTag(w io.Writer, name string, tag Tag) error {
+	return WriteCompressedNamedTag(w, Zlib, name, tag)
+}
+
+// DetectCompression guesses the compression of the stream read by r from its first bytes, without consuming them.
+// Gzip and LZ4 are recognized by their magic numbers, Zlib by its header. Anything else is considered uncompressed, since a NBT
+// stream starts with a tag type, which can't be confused with these headers.
+//
+// If r is empty, io.EOF is returned.
+func DetectCompression(r *bufio.Reader) (Compression, error) {
+	head, err := r.Peek(len(lz4Magic))
+	switch {
+	case len(head) == 0 && err != nil:
+		return NoCompression, err
+	case len(head) < 2:
+		return NoCompression, nil
+	case string(head) == lz4Magic:
+		return LZ4, nil
+	case head[0] == 0x1f && head[1] == 0x8b:
+		return Gzip, nil
+	case head[0]&0x0f == 8 && head[0]>>4 >= 1 && head[0]>>4 <= 7 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0:
+		// Zlib header: compression method deflate, window size 512 B to 32 KiB, check bits. The tiny window size 256
+		// is excluded, since its first byte would equal TAG_String.
+		return Zlib, nil
+	}
+	return NoCompression, nil
+}
+
+// ReadAnyNamedTag reads a named tag that is either uncompressed, gzip, zlib or LZ4 compressed (see DetectCompression).
+// It returns the Tag, the tags name and the detected compression, so the data can be written back the same way with
+// WriteCompressedNamedTag.
+//
+// Since the input is buffered, more data than necessary may be read from r.
+func ReadAnyNamedTag(r io.Reader) (Tag, string, Compression, error) {
+	br := bufio.NewReader(r)
+	c, err := DetectCompression(br)
+	if err != nil {
+		return Tag{}, "", c, err
+	}
+	tag, name, err := readCompressedNamedTag(br, c)
+	return tag, name, c, err
 }
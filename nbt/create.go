@@ -1,18 +1,20 @@
 package nbt
 
 import (
+	"fmt"
 	"reflect"
 )
 
-func NewByteTag(v byte) Tag        { return Tag{TAG_Byte, v} }
-func NewShortTag(v int16) Tag      { return Tag{TAG_Short, v} }
-func NewIntTag(v int32) Tag        { return Tag{TAG_Int, v} }
-func NewLongTag(v int64) Tag       { return Tag{TAG_Long, v} }
-func NewFloatTag(v float32) Tag    { return Tag{TAG_Float, v} }
-func NewDoubleTag(v float64) Tag   { return Tag{TAG_Double, v} }
-func NewByteArrayTag(v []byte) Tag { return Tag{TAG_Byte_Array, v} }
-func NewStringTag(v string) Tag    { return Tag{TAG_String, v} }
-func NewIntArrayTag(v []int32) Tag { return Tag{TAG_Int_Array, v} }
+func NewByteTag(v byte) Tag         { return Tag{TAG_Byte, v} }
+func NewShortTag(v int16) Tag       { return Tag{TAG_Short, v} }
+func NewIntTag(v int32) Tag         { return Tag{TAG_Int, v} }
+func NewLongTag(v int64) Tag        { return Tag{TAG_Long, v} }
+func NewFloatTag(v float32) Tag     { return Tag{TAG_Float, v} }
+func NewDoubleTag(v float64) Tag    { return Tag{TAG_Double, v} }
+func NewByteArrayTag(v []byte) Tag  { return Tag{TAG_Byte_Array, v} }
+func NewStringTag(v string) Tag     { return Tag{TAG_String, v} }
+func NewIntArrayTag(v []int32) Tag  { return Tag{TAG_Int_Array, v} }
+func NewLongArrayTag(v []int64) Tag { return Tag{TAG_Long_Array, v} }
 
 // NewCompoundTag creates a new Tag with type TAG_Compound. Usually it is more convenient to make the TagCompound payload and then manually construct the Tag value, though.
 func NewCompoundTag() Tag { return Tag{TAG_Compound, make(TagCompound)} }
@@ -23,6 +25,7 @@ func NewCompoundTag() Tag { return Tag{TAG_Compound, make(TagCompound)} }
 //
 // YOU are responsible for this, this function will not check the correctness.
 // When given wrong data, this function will either panic, or writing the NBT data will later fail.
+// Use NewListTagChecked for data that is not known to be correct.
 func NewListTag(ltt TagType, l interface{}) Tag {
 	var elems []interface{}
 	if is, ok := l.([]interface{}); ok {
@@ -40,3 +43,19 @@ func NewListTag(ltt TagType, l interface{}) Tag {
 
 	return Tag{TAG_List, TagList{ltt, elems}}
 }
+
+// NewListTagChecked is like NewListTag, but returns an error instead of panicking, if l is not a slice, and validates
+// the elements (see Tag.Validate).
+func NewListTagChecked(ltt TagType, l interface{}) (Tag, error) {
+	if _, ok := l.([]interface{}); !ok {
+		if l == nil || reflect.TypeOf(l).Kind() != reflect.Slice {
+			return Tag{}, &ValidationError{"", TAG_List, fmt.Sprintf("elements must be given as a slice, not %T", l)}
+		}
+	}
+
+	tag := NewListTag(ltt, l)
+	if err := tag.Validate(); err != nil {
+		return Tag{}, err
+	}
+	return tag, nil
+}
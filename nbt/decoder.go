@@ -0,0 +1,265 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrLimitExceeded matches every *LimitError (use errors.Is).
+var ErrLimitExceeded = errors.New("nbt: limit exceeded")
+
+// LimitError is returned by a Decoder, if the data exceeds one of its Limits.
+type LimitError struct {
+	Limit string // Name of the exceeded field of Limits, e.g. "MaxDepth".
+	Max   int64  // The configured limit.
+	Value int64  // The value that exceeded the limit.
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("nbt: limit exceeded: %s is %d, have %d", e.Limit, e.Max, e.Value)
+}
+
+// Is makes errors.Is(err, ErrLimitExceeded) true for a *LimitError.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// Limits restrict the data a Decoder accepts. A zero value means no limit, except for MaxDepth: as compounds and lists
+// are decoded recursively, a zero MaxDepth means DefaultMaxDepth.
+//
+// Independent of the limits, a Decoder never allocates much more memory for an array, list or string than the data
+// it actually read, so a short input declaring a huge length fails with io.ErrUnexpectedEOF instead of exhausting memory.
+type Limits struct {
+	MaxBytes     int64 // Maximum size of a single root tag in bytes (after decompression).
+	MaxDepth     int   // Maximum nesting depth of compounds and lists.
+	MaxElems     int   // Maximum number of elements of a single list or array.
+	MaxStringLen int   // Maximum length of a string (including tag names) in bytes, as stored.
+}
+
+// DefaultMaxDepth is the nesting depth a Decoder accepts, if Limits.MaxDepth is zero. It is the limit of Minecraft.
+const DefaultMaxDepth = 512
+
+// UntrustedLimits are reasonable limits for NBT data from untrusted sources, like uploaded schematics or worlds.
+var UntrustedLimits = Limits{MaxBytes: 64 * 1024 * 1024, MaxDepth: 512, MaxElems: 16 * 1024 * 1024, MaxStringLen: math.MaxInt16}
+
+// NetworkLimits are the limits used for network NBT. MaxBytes and MaxDepth match the limits of the Minecraft server,
+// which counts at least 4 bytes of memory per list or array element, so lists and arrays are limited to a quarter of
+// MaxBytes. Strings are limited to the maximum length Java Edition can store, for all formats.
+var NetworkLimits = Limits{MaxBytes: 2 * 1024 * 1024, MaxDepth: 512, MaxElems: 512 * 1024, MaxStringLen: math.MaxInt16}
+
+// allocChunk is the number of elements allocated in advance for arrays and lists, since their declared length can't be trusted.
+const allocChunk = 4096
+
+// DecodeError is returned by a Decoder, if the data can't be decoded. It tells where the error occurred.
+// Use errors.Is and errors.As to inspect the cause, e.g. errors.Is(err, io.ErrUnexpectedEOF).
+type DecodeError struct {
+	Offset int64   // Offset in the (decompressed) stream, where the error occurred.
+	Path   string  // Path of the tag that was read, relative to the root tag (e.g. "Level.Sections[3].BlockStates"). Empty for the root tag.
+	Type   TagType // Type of the tag that was read. TAG_End, if the error occurred outside of a tag (e.g. in a compression header).
+	Err    error   // The cause.
+}
+
+func (e *DecodeError) Error() string {
+	s := fmt.Sprintf("nbt: offset %d", e.Offset)
+	if e.Type != TAG_End {
+		s += fmt.Sprintf(", %s at %s", e.Type, pathOrRoot(e.Path))
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Decoder reads NBT data from an input stream. It can read several consecutive root tags from one stream.
+//
+// It builds Tags from the tokens of a Tokenizer.
+type Decoder struct {
+	t Tokenizer
+}
+
+// NewDecoder returns a new Decoder that reads from r. By default it reads uncompressed, big-endian data (as used by Java Edition).
+//
+// The Decoder buffers its input and may read more data than necessary from r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := newDecoder(r)
+	d.t.buffered = true
+	return d
+}
+
+// NewBytesDecoder returns a new Decoder that reads from data. Reading from memory is faster than reading from an
+// io.Reader. See also AliasInput.
+func NewBytesDecoder(data []byte) *Decoder {
+	d := new(Decoder)
+	d.t.initBytes(data)
+	return d
+}
+
+// newDecoder returns an unbuffered Decoder that reads no more data from r than necessary.
+func newDecoder(r io.Reader) *Decoder {
+	d := new(Decoder)
+	d.t.init(r)
+	return d
+}
+
+// SetByteOrder sets the byte order of numbers. Java Edition uses binary.BigEndian (the default), Bedrock Edition binary.LittleEndian.
+func (d *Decoder) SetByteOrder(order binary.ByteOrder) { d.t.SetByteOrder(order) }
+
+// UseNetworkFormat makes the Decoder read the network NBT format of Bedrock Edition. It stores TAG_Int and TAG_Long values
+// as zigzag encoded varints and the lengths of strings, lists and arrays as varints. All other numbers are little-endian,
+// strings are UTF-8.
+//
+// Network data should not be trusted, so consider using NetworkLimits.
+func (d *Decoder) UseNetworkFormat() { d.t.UseNetworkFormat() }
+
+// SetStringEncoding sets the encoding of strings. The default is ModifiedUTF8.
+func (d *Decoder) SetStringEncoding(enc StringEncoding) { d.t.SetStringEncoding(enc) }
+
+// UseNamelessRoot makes the Decoder read root tags without a name, as sent in network packets since Java Edition 1.20.2.
+// The type of the root tag is directly followed by its payload. A root tag of type TAG_End (an empty item slot, for example)
+// is returned as a Tag with Type TAG_End.
+func (d *Decoder) UseNamelessRoot() { d.t.UseNamelessRoot() }
+
+// SetLimits sets the limits for decoded data.
+func (d *Decoder) SetLimits(l Limits) { d.t.SetLimits(l) }
+
+// AliasInput makes byte arrays and string values share memory with the input of a Decoder created with NewBytesDecoder,
+// instead of copying them. Strings are only shared if their encoding allows it, i.e. if they only contain ASCII
+// characters when reading modified UTF-8. Names are always copied.
+//
+// The input must not be modified afterwards, as long as the decoded tags are in use. Has no effect with compression.
+func (d *Decoder) AliasInput() { d.t.alias = true }
+
+// InputOffset returns the number of bytes of the input that have been consumed. See Tokenizer.InputOffset.
+func (d *Decoder) InputOffset() int64 { return d.t.InputOffset() }
+
+// SetCompression sets the compression of the input stream. It must be called before the first call to Decode.
+func (d *Decoder) SetCompression(c Compression) { d.t.SetCompression(c) }
+
+// Decode reads the next named root tag from the stream. It returns the Tag, the tags name and an error.
+//
+// If the stream ends before the next tag, Decode returns io.EOF. Other errors are returned as *DecodeError; if the stream
+// ends within a tag, its cause is io.ErrUnexpectedEOF.
+//
+// With UseNamelessRoot, the name is always empty.
+func (d *Decoder) Decode() (Tag, string, error) {
+	tok, err := d.t.Next()
+	if err != nil {
+		return Tag{}, "", err
+	}
+
+	td, err := d.readTagData(tok)
+	return Tag{Type: tok.Type, Payload: td}, tok.Name, err
+}
+
+// DecodeOrdered is like Decode, but also returns the order of the compound entries in the tag. Writing the tag with
+// Encoder.EncodeOrdered reproduces the input, unless a compound contained a name more than once.
+func (d *Decoder) DecodeOrdered() (Tag, string, *KeyOrder, error) {
+	tok, err := d.t.Next()
+	if err != nil {
+		return Tag{}, "", nil, err
+	}
+
+	td, order, err := d.readOrderedTagData(tok, true)
+	return Tag{Type: tok.Type, Payload: td}, tok.Name, order, err
+}
+
+// DecodePayload reads the payload of a tag of type tt from the stream. It is used, if the type is known in advance and
+// neither the type nor a name precede the payload.
+func (d *Decoder) DecodePayload(tt TagType) (Tag, error) {
+	tok, err := d.t.NextPayload(tt)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	td, err := d.readTagData(tok)
+	return Tag{Type: tt, Payload: td}, err
+}
+
+// initialCap returns the capacity to allocate in advance for l elements.
+func initialCap(l int) int {
+	if l > allocChunk {
+		return allocChunk
+	}
+	return l
+}
+
+// readTagData reads the payload of the tag started by tok. Errors are returned as *DecodeError.
+func (d *Decoder) readTagData(tok Token) (interface{}, error) {
+	td, _, err := d.readOrderedTagData(tok, false)
+	return td, err
+}
+
+// readOrderedTagData is readTagData, that also returns the KeyOrder of the tag, if ordered is set.
+func (d *Decoder) readOrderedTagData(tok Token, ordered bool) (interface{}, *KeyOrder, error) {
+	switch tok.Kind {
+	case TokenValue:
+		return tok.Value, nil, nil
+	case TokenArrayStart:
+		data, err := d.t.readArray()
+		return data, nil, err
+	case TokenListStart:
+		var order *KeyOrder
+		if ordered {
+			order = new(KeyOrder)
+		}
+		tl := TagList{Type: tok.ElemType, Elems: make([]interface{}, 0, initialCap(tok.Len))}
+		for {
+			etok, err := d.t.Next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if etok.Kind == TokenListEnd {
+				if order != nil && order.Children == nil {
+					order = nil
+				}
+				return tl, order, nil
+			}
+
+			elem, eorder, err := d.readOrderedTagData(etok, ordered)
+			if err != nil {
+				return nil, nil, err
+			}
+			if order != nil {
+				order.setChild(len(tl.Elems), eorder)
+			}
+			tl.Elems = append(tl.Elems, elem)
+		}
+	case TokenCompoundStart:
+		var order *KeyOrder
+		if ordered {
+			order = &KeyOrder{Names: []string{}}
+		}
+		comp := make(TagCompound)
+		for {
+			etok, err := d.t.Next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if etok.Kind == TokenCompoundEnd {
+				return comp, order, nil
+			}
+
+			td, eorder, err := d.readOrderedTagData(etok, ordered)
+			if err != nil {
+				return nil, nil, err
+			}
+			if order != nil {
+				i := len(order.Names)
+				if _, dup := comp[etok.Name]; dup {
+					for i = range order.Names {
+						if order.Names[i] == etok.Name {
+							break
+						}
+					}
+				} else {
+					order.Names = append(order.Names, etok.Name)
+				}
+				order.setChild(i, eorder)
+			}
+			comp[etok.Name] = Tag{Type: etok.Type, Payload: td}
+		}
+	}
+	return nil, nil, fmt.Errorf("nbt: unexpected token %s", tok.Kind)
+}
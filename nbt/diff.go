@@ -0,0 +1,440 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+// Valid ChangeKind values.
+const (
+	Added        ChangeKind = iota + 1 // A compound entry or list element was added. Uses New.
+	Removed                            // A compound entry or list element was removed. Uses Old.
+	TypeChanged                        // The type of a tag changed. Uses Old and New.
+	ValueChanged                       // The value of a tag changed. Uses Old and New.
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "add"
+	case Removed:
+		return "remove"
+	case TypeChanged:
+		return "type"
+	case ValueChanged:
+		return "value"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a difference between two tags, found by Diff.
+//
+// Path is a concrete path (only names and non-negative indices). List indices refer to the list after applying all
+// earlier changes of the same diff, so the changes must be applied in order.
+type Change struct {
+	Kind     ChangeKind
+	Path     Path
+	Old, New Tag
+}
+
+// String formats c as a line of a patch (see FormatPatch).
+func (c Change) String() string {
+	path := c.Path.String()
+	if path == "" {
+		path = "."
+	}
+
+	s := c.Kind.String() + " " + path + " "
+	switch c.Kind {
+	case Added:
+		return s + formatPatchValue(c.New)
+	case Removed:
+		return s + formatPatchValue(c.Old)
+	}
+	return s + formatPatchValue(c.Old) + " => " + formatPatchValue(c.New)
+}
+
+// formatPatchValue formats tag as SNBT, or as typed JSON prefixed by "json:", if SNBT can't represent it exactly (e.g.
+// empty lists of a type other than TAG_End, NaN or infinite floats).
+func formatPatchValue(tag Tag) string {
+	s := FormatSNBT(tag, SNBTOptions{})
+	if parsed, err := ParseSNBT(s); err == nil && Equal(parsed, tag) {
+		return s
+	}
+	if data, err := ToJSON(tag, JSONOptions{Mode: TypedJSON}); err == nil {
+		return "json:" + string(data)
+	}
+	return s
+}
+
+// parsePatchValue parses a value formatted by formatPatchValue.
+func parsePatchValue(p *snbtParser) (Tag, error) {
+	if p.peek() != 'j' || !strings.HasPrefix(p.s[p.pos:], "json:") {
+		return p.parseValue()
+	}
+
+	p.pos += len("json:")
+	dec := json.NewDecoder(strings.NewReader(p.s[p.pos:]))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return Tag{}, p.errorf("invalid JSON: %s", err)
+	}
+	tag, err := FromJSON(raw, JSONOptions{Mode: TypedJSON})
+	if err != nil {
+		return Tag{}, p.errorf("invalid JSON: %s", err)
+	}
+	p.pos += int(dec.InputOffset())
+	return tag, nil
+}
+
+// maxAlignCells limits the size of the table used to align lists. Larger lists are compared element by element.
+// The lengths of the common subsequences are at most 1024 then, so they fit into uint16 cells (2 MiB in total).
+const maxAlignCells = 1 << 20
+
+// Diff returns the changes that turn a into b.
+//
+// Compound entries are compared by name. Lists are aligned by their longest common subsequence of equal elements;
+// the remaining elements between are compared pairwise, surplus elements are added or removed. Arrays of the same
+// length are compared element by element, other arrays and lists with different element types are changed as a whole.
+func Diff(a, b Tag) []Change {
+	var changes []Change
+	diff(&changes, Path{}, a, b)
+	return changes
+}
+
+func diff(changes *[]Change, path Path, a, b Tag) {
+	if a.Type != b.Type {
+		*changes = append(*changes, Change{TypeChanged, path, a, b})
+		return
+	}
+
+	switch a.Type {
+	case TAG_Compound:
+		diffCompounds(changes, path, a, b)
+		return
+	case TAG_List:
+		la, ok1 := a.Payload.(TagList)
+		lb, ok2 := b.Payload.(TagList)
+		if ok1 && ok2 && la.Type == lb.Type {
+			diffLists(changes, path, la, lb)
+			return
+		}
+	case TAG_Byte_Array, TAG_Int_Array, TAG_Long_Array:
+		if n := elemCount(a); n > 0 && n == elemCount(b) {
+			for i := 0; i < n; i++ {
+				if ea, eb := elemAt(a, i), elemAt(b, i); !Equal(ea, eb) {
+					*changes = append(*changes, Change{ValueChanged, path.Index(i), ea, eb})
+				}
+			}
+			return
+		}
+	}
+
+	if !Equal(a, b) {
+		*changes = append(*changes, Change{ValueChanged, path, a, b})
+	}
+}
+
+func diffCompounds(changes *[]Change, path Path, a, b Tag) {
+	ca, cb := a.Payload.(TagCompound), b.Payload.(TagCompound)
+	names := make([]string, 0, len(ca)+len(cb))
+	for name := range ca {
+		names = append(names, name)
+	}
+	for name := range cb {
+		if _, ok := ca[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ta, inA := ca[name]
+		tb, inB := cb[name]
+		switch {
+		case !inB:
+			*changes = append(*changes, Change{Removed, path.Key(name), ta, Tag{}})
+		case !inA:
+			*changes = append(*changes, Change{Added, path.Key(name), Tag{}, tb})
+		default:
+			diff(changes, path.Key(name), ta, tb)
+		}
+	}
+}
+
+func diffLists(changes *[]Change, path Path, la, lb TagList) {
+	n, m := len(la.Elems), len(lb.Elems)
+	elemA := func(i int) Tag { return Tag{la.Type, la.Elems[i]} }
+	elemB := func(j int) Tag { return Tag{lb.Type, lb.Elems[j]} }
+
+	i, j, k := 0, 0, 0 // k is the index in the list with all changes so far applied.
+	gap := func(iEnd, jEnd int) {
+		for ; i < iEnd && j < jEnd; i, j, k = i+1, j+1, k+1 {
+			diff(changes, path.Index(k), elemA(i), elemB(j))
+		}
+		for ; i < iEnd; i++ {
+			*changes = append(*changes, Change{Removed, path.Index(k), elemA(i), Tag{}})
+		}
+		for ; j < jEnd; j, k = j+1, k+1 {
+			*changes = append(*changes, Change{Added, path.Index(k), Tag{}, elemB(j)})
+		}
+	}
+
+	// Equal elements at the start and the end need no alignment.
+	for i < n && j < m && Equal(elemA(i), elemB(j)) {
+		i, j, k = i+1, j+1, k+1
+	}
+	endA, endB := n, m
+	for endA > i && endB > j && Equal(elemA(endA-1), elemB(endB-1)) {
+		endA, endB = endA-1, endB-1
+	}
+
+	if rows, cols := endA-i, endB-j; rows > 0 && cols > 0 && rows*cols <= maxAlignCells {
+		ids := elemIDs(la.Type, la.Elems[i:endA], lb.Elems[j:endB])
+		idA, idB := ids[:rows], ids[rows:]
+
+		// lcs[x*w+y] is the length of the longest common subsequence of the elements from x and y on.
+		w := cols + 1
+		lcs := make([]uint16, (rows+1)*w)
+		for x := rows - 1; x >= 0; x-- {
+			for y := cols - 1; y >= 0; y-- {
+				switch {
+				case idA[x] == idB[y]:
+					lcs[x*w+y] = lcs[(x+1)*w+y+1] + 1
+				case lcs[(x+1)*w+y] >= lcs[x*w+y+1]:
+					lcs[x*w+y] = lcs[(x+1)*w+y]
+				default:
+					lcs[x*w+y] = lcs[x*w+y+1]
+				}
+			}
+		}
+
+		i0, j0 := i, j
+		for x, y := 0, 0; x < rows && y < cols; {
+			switch {
+			case idA[x] == idB[y]:
+				gap(i0+x, j0+y)
+				i, j, k = i+1, j+1, k+1
+				x, y = x+1, y+1
+			case lcs[(x+1)*w+y] >= lcs[x*w+y+1]:
+				x++
+			default:
+				y++
+			}
+		}
+	}
+	gap(endA, endB)
+}
+
+// elemIDs numbers the elements of a and b (both of type tt), so that equal elements have the same number. The numbers
+// of a come first in the result. Elements are compared with Equal only, if the hashes of their encodings are equal.
+func elemIDs(tt TagType, a, b []interface{}) []int {
+	enc := appender{order: binary.BigEndian, sortKeys: true}
+	var buf []byte
+	buckets := make(map[uint64][]int) // IDs by hash
+	var reps []interface{}            // An element for each ID.
+
+	ids := make([]int, 0, len(a)+len(b))
+	for _, elems := range [][]interface{}{a, b} {
+		for _, e := range elems {
+			// An invalid element is hashed as far as it could be encoded, Equal decides then.
+			buf, _ = enc.appendTagData(buf[:0], tt, e)
+			h := fnv.New64a()
+			h.Write(buf)
+			sum := h.Sum64()
+
+			id := -1
+			for _, rep := range buckets[sum] {
+				if Equal(Tag{tt, reps[rep]}, Tag{tt, e}) {
+					id = rep
+					break
+				}
+			}
+			if id < 0 {
+				id = len(reps)
+				reps = append(reps, e)
+				buckets[sum] = append(buckets[sum], id)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ErrConflict is the cause of a PatchError, if the tag does not match the state a change expects.
+var ErrConflict = errors.New("nbt: change does not match tag")
+
+// PatchError is returned by Apply, if a change could not be applied.
+type PatchError struct {
+	Index  int // Index of the change.
+	Change Change
+	Err    error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("nbt: can not apply change %d (%s): %s", e.Index, e.Change, e.Err)
+}
+
+func (e *PatchError) Unwrap() error { return e.Err }
+
+// Apply applies changes (as returned by Diff) to tag in order. Before each change, the current value is compared with
+// the old value of the change; if they differ, a PatchError with the cause ErrConflict is returned. Changes made before
+// an error are kept.
+func Apply(tag *Tag, changes []Change) error {
+	for i, c := range changes {
+		if err := applyChange(tag, c); err != nil {
+			return &PatchError{i, c, err}
+		}
+	}
+	return nil
+}
+
+func applyChange(tag *Tag, c Change) error {
+	cur := c.Path.Get(*tag)
+	switch c.Kind {
+	case Added:
+		if len(c.Path) == 0 {
+			return ErrConflict
+		}
+		parent, last := c.Path[:len(c.Path)-1], c.Path[len(c.Path)-1]
+		if len(parent.Get(*tag)) != 1 {
+			return ErrConflict
+		}
+		if last.Kind == PathIndex {
+			_, err := tag.Insert(parent, last.Index, c.New)
+			return err
+		}
+		if len(cur) != 0 {
+			return ErrConflict
+		}
+		_, err := tag.Set(c.Path, c.New)
+		return err
+	case Removed:
+		if len(cur) != 1 || !Equal(cur[0].Tag, c.Old) {
+			return ErrConflict
+		}
+		_, err := tag.Remove(c.Path)
+		return err
+	case TypeChanged, ValueChanged:
+		if len(cur) != 1 || !Equal(cur[0].Tag, c.Old) {
+			return ErrConflict
+		}
+		_, err := tag.Set(c.Path, c.New)
+		return err
+	}
+	return fmt.Errorf("unknown change kind %d", c.Kind)
+}
+
+// FormatPatch formats changes as text, one change per line:
+//
+//	add <path> <new value>
+//	remove <path> <old value>
+//	value <path> <old value> => <new value>
+//	type <path> <old value> => <new value>
+//
+// Values are formatted as SNBT, the root path is written as ".". Values SNBT can't represent exactly, like empty lists
+// of a type other than TAG_End and NaN or infinite floats, are written as typed JSON (see ToJSON) prefixed by "json:".
+// Lines starting with # are comments.
+func FormatPatch(changes []Change) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ParsePatch parses a patch formatted by FormatPatch. Syntax errors are returned as *SNBTSyntaxError.
+func ParsePatch(s string) ([]Change, error) {
+	p := &snbtParser{s: s}
+	var changes []Change
+	for {
+		if p.peek() == '#' {
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if p.pos >= len(p.s) {
+			return changes, nil
+		}
+
+		c, err := parseChange(p)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+}
+
+func parseChange(p *snbtParser) (Change, error) {
+	var c Change
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z' {
+		p.pos++
+	}
+	switch p.s[start:p.pos] {
+	case "add":
+		c.Kind = Added
+	case "remove":
+		c.Kind = Removed
+	case "value":
+		c.Kind = ValueChanged
+	case "type":
+		c.Kind = TypeChanged
+	default:
+		p.pos = start
+		return c, p.errorf("expected add, remove, value or type")
+	}
+
+	if p.pos >= len(p.s) || p.s[p.pos] != ' ' {
+		return c, p.errorf("expected ' '")
+	}
+	p.pos++
+	if strings.HasPrefix(p.s[p.pos:], ". ") {
+		c.Path = Path{}
+		p.pos++
+	} else {
+		var err error
+		if c.Path, err = parsePath(p); err != nil {
+			return c, err
+		}
+	}
+
+	v, err := parsePatchValue(p)
+	if err != nil {
+		return c, err
+	}
+	switch c.Kind {
+	case Added:
+		c.New = v
+	case Removed:
+		c.Old = v
+	default:
+		c.Old = v
+		if p.peek() != '=' || !strings.HasPrefix(p.s[p.pos:], "=>") {
+			return c, p.errorf("expected '=>'")
+		}
+		p.pos += 2
+		if c.New, err = parsePatchValue(p); err != nil {
+			return c, err
+		}
+	}
+
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+	if p.pos < len(p.s) && p.s[p.pos] != '\n' {
+		return c, p.errorf("expected end of line")
+	}
+	return c, nil
+}
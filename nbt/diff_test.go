@@ -0,0 +1,208 @@
+package nbt
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := mustSNBT(t, `{name:"Steve",health:20.0f,xp:5,pos:[1.0d,2.0d,3.0d],
+		inv:[{id:"a",n:1b},{id:"b",n:1b},{id:"c",n:1b}],
+		arr:[I;1,2,3],gone:1b}`)
+	b := mustSNBT(t, `{name:"Steve",health:19.5f,xp:5s,pos:[1.0d,2.0d,3.0d],
+		inv:[{id:"x",n:1b},{id:"b",n:1b},{id:"c",n:2b},{id:"d",n:1b}],
+		arr:[I;1,4,3],new:{}}`)
+
+	want := []string{
+		`value arr[1] 2 => 4`,
+		`remove gone 1b`,
+		`value health 20.0f => 19.5f`,
+		`value inv[0].id "a" => "x"`,
+		`value inv[2].n 1b => 2b`,
+		`add inv[3] {id:"d",n:1b}`,
+		`add new {}`,
+		`type xp 5 => 5s`,
+	}
+
+	changes := Diff(a, b)
+	if len(changes) != len(want) {
+		t.Fatalf("Want %d changes, have %d:\n%s", len(want), len(changes), FormatPatch(changes))
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("Change %d: want %s, have %s", i, want[i], c)
+		}
+	}
+
+	if err := Apply(&a, changes); err != nil {
+		t.Fatalf("Could not apply diff: %s", err)
+	}
+	if len(Diff(a, b)) != 0 {
+		t.Errorf("Applied diff does not reproduce b:\n%s", FormatPatch(Diff(a, b)))
+	}
+}
+
+func TestDiffListAlignment(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{`[1,2,3]`, `[0,1,2,3]`, []string{`add [0] 0`}},
+		{`[1,2,3]`, `[1,3]`, []string{`remove [1] 2`}},
+		{`[1,2,3,4]`, `[1,9,8,4,5]`, []string{`value [1] 2 => 9`, `value [2] 3 => 8`, `add [4] 5`}},
+		{`[1,2,3]`, `[3,2,1]`, []string{`remove [0] 1`, `remove [0] 2`, `add [1] 2`, `add [2] 1`}},
+		{`["a","b","c"]`, `["b"]`, []string{`remove [0] "a"`, `remove [1] "c"`}},
+		{`[1,2]`, `[1b,2b]`, []string{`value . [1,2] => [1b,2b]`}},
+	}
+
+	for _, test := range tests {
+		a, b := mustSNBT(t, test.a), mustSNBT(t, test.b)
+		changes := Diff(a, b)
+		if FormatPatch(changes) != FormatPatch(mustParsePatch(t, test.want)) {
+			t.Errorf("%s -> %s: Want\n%s\nHave\n%s", test.a, test.b, FormatPatch(mustParsePatch(t, test.want)), FormatPatch(changes))
+			continue
+		}
+		if err := Apply(&a, changes); err != nil {
+			t.Errorf("%s -> %s: Could not apply: %s", test.a, test.b, err)
+		} else if !Equal(a, b) && FormatSNBT(a, SNBTOptions{}) != FormatSNBT(b, SNBTOptions{}) {
+			t.Errorf("%s -> %s: Apply resulted in %s", test.a, test.b, FormatSNBT(a, SNBTOptions{}))
+		}
+	}
+}
+
+func TestDiffLargeLists(t *testing.T) {
+	entity := func(i int) interface{} {
+		return TagCompound{"id": NewIntTag(int32(i)), "pos": NewListTag(TAG_Double, []float64{float64(i), 64, 0})}
+	}
+	var ea, eb []interface{}
+	for i := 0; i < 1000; i++ {
+		ea = append(ea, entity(i))
+		if i == 10 {
+			eb = append(eb, entity(-1))
+		}
+		if i != 900 {
+			eb = append(eb, entity(i))
+		}
+	}
+
+	// The elements at the start and the end are equal, so only the middle is aligned.
+	changes := Diff(NewListTag(TAG_Compound, append([]interface{}{entity(-2)}, ea...)), NewListTag(TAG_Compound, ea))
+	if len(changes) != 1 || changes[0].Kind != Removed {
+		t.Errorf("Want one removed element, have\n%s", FormatPatch(changes))
+	}
+
+	changes = Diff(NewListTag(TAG_Compound, ea), NewListTag(TAG_Compound, eb))
+	if len(changes) != 2 || changes[0].Kind != Added || changes[1].Kind != Removed {
+		t.Errorf("Want an added and a removed element, have\n%s", FormatPatch(changes))
+	}
+
+	// Lists that are too large to align are compared element by element.
+	for i := 0; i < 2000; i++ {
+		ea = append(ea, entity(i))
+	}
+	changes = Diff(NewListTag(TAG_Compound, ea), NewListTag(TAG_Compound, append([]interface{}{entity(-1)}, ea[:len(ea)-1]...)))
+	if len(changes) != 2*len(ea) { // id and pos of every element
+		t.Errorf("Want %d changes, have %d", 2*len(ea), len(changes))
+	}
+}
+
+func mustParsePatch(t *testing.T, lines []string) []Change {
+	s := ""
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	changes, err := ParsePatch(s)
+	if err != nil {
+		t.Fatalf("Could not parse patch %q: %s", s, err)
+	}
+	return changes
+}
+
+func TestPatchRoundtrip(t *testing.T) {
+	// Strings may contain line breaks.
+	a := mustSNBT(t, "{\"odd key\":{list:[\"x\ny\"]},n:1}")
+	b := mustSNBT(t, "{\"odd key\":{list:[\"x\ny\",\"z\"]},n:1L}")
+	changes := Diff(a, b)
+
+	patch := "# A comment\n\n" + FormatPatch(changes)
+	parsed, err := ParsePatch(patch)
+	if err != nil {
+		t.Fatalf("Could not parse patch:\n%s\n%s", patch, err)
+	}
+	if FormatPatch(parsed) != FormatPatch(changes) {
+		t.Errorf("Patch changed.\nWant:\n%s\nHave:\n%s", FormatPatch(changes), FormatPatch(parsed))
+	}
+	if err := Apply(&a, parsed); err != nil {
+		t.Fatalf("Could not apply parsed patch: %s", err)
+	}
+	if !Equal(a, b) {
+		t.Errorf("Wrong result %s", FormatSNBT(a, SNBTOptions{}))
+	}
+
+	for _, bad := range []string{"frob a 1\n", "add a\n", "value a 1 2\n", "add a 1 2\n", "add a[ 1\n"} {
+		if _, err := ParsePatch(bad); err == nil {
+			t.Errorf("Parsing %q should fail", bad)
+		}
+	}
+}
+
+func TestPatchRoundtripTypes(t *testing.T) {
+	a := Tag{TAG_Compound, TagCompound{
+		"ints":   NewListTag(TAG_Int, []int32{}),
+		"f":      NewFloatTag(1),
+		"d":      NewDoubleTag(math.NaN()),
+		"empty":  NewListTag(TAG_String, []string{}),
+		"nested": Tag{TAG_Compound, TagCompound{"l": NewListTag(TAG_Long, []int64{})}},
+	}}
+	b := Tag{TAG_Compound, TagCompound{
+		"ints":   NewListTag(TAG_Int, []int32{1}),
+		"f":      NewFloatTag(float32(math.Inf(-1))),
+		"d":      NewDoubleTag(2),
+		"empty":  NewListTag(TAG_Compound, []interface{}{}),
+		"nested": Tag{TAG_Compound, TagCompound{"l": NewListTag(TAG_Long, []int64{}), "x": NewDoubleTag(math.Inf(1))}},
+	}}
+
+	patch := FormatPatch(Diff(a, b))
+	changes, err := ParsePatch(patch)
+	if err != nil {
+		t.Fatalf("Could not parse patch:\n%s\n%s", patch, err)
+	}
+	if err := Apply(&a, changes); err != nil {
+		t.Fatalf("Could not apply patch:\n%s\n%s", patch, err)
+	}
+	if !Equal(a, b) {
+		t.Errorf("Wrong result %s", FormatSNBT(a, SNBTOptions{}))
+	}
+
+	if _, err := ParsePatch("add x json:{\"type\":\"TAG_Nope\"}\n"); err == nil {
+		t.Errorf("Invalid JSON value should fail")
+	}
+}
+
+func TestApplyConflict(t *testing.T) {
+	tag := mustSNBT(t, `{a:1,b:[1,2]}`)
+	for _, patch := range []string{
+		"value a 2 => 3",
+		"remove b[5] 1",
+		"add a 5",
+		"add c.d 5",
+		"type . {} => 1",
+	} {
+		changes, err := ParsePatch(patch)
+		if err != nil {
+			t.Fatalf("Could not parse %q: %s", patch, err)
+		}
+		err = Apply(&tag, changes)
+		var pe *PatchError
+		if !errors.As(err, &pe) || !errors.Is(err, ErrConflict) {
+			t.Errorf("%s: Want conflict, have %v", patch, err)
+		}
+	}
+
+	changes, _ := ParsePatch("add b[0] 1s")
+	var me *ModifyError
+	if err := Apply(&tag, changes); !errors.As(err, &me) {
+		t.Errorf("Adding a short to a list of ints: want ModifyError, have %v", err)
+	}
+}
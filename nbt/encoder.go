@@ -0,0 +1,542 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"unicode/utf8"
+)
+
+// Encoder writes NBT data to an output stream. It can write several consecutive root tags to one stream.
+//
+// Each root tag is serialized into a reused buffer and then written with a single call to Write, so the output doesn't
+// need to be buffered.
+type Encoder struct {
+	appender
+
+	dst      io.Writer
+	cw       io.WriteCloser // Compressor, if compression is used.
+	started  bool
+	nameless bool
+	comp     Compression
+}
+
+// appender serializes tags by appending them to a byte slice.
+type appender struct {
+	order    binary.ByteOrder
+	network  bool
+	sortKeys bool
+	strings  StringEncoding
+
+	path Path      // Path to the tag currently written, for errors.
+	keys *KeyOrder // KeyOrder of the tag currently written, if any.
+}
+
+// NewEncoder returns a new Encoder that writes to w. By default it writes uncompressed, big-endian data (as used by Java Edition).
+//
+// Without compression, the data is written to w by every call to Encode. If compression is used, Close must be called after the last tag.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{dst: w, appender: appender{order: binary.BigEndian}}
+}
+
+// SetByteOrder sets the byte order of numbers. Java Edition uses binary.BigEndian (the default), Bedrock Edition binary.LittleEndian.
+func (e *Encoder) SetByteOrder(order binary.ByteOrder) { e.order = order }
+
+// UseNetworkFormat makes the Encoder write the network NBT format of Bedrock Edition. See Decoder.UseNetworkFormat.
+func (e *Encoder) UseNetworkFormat() {
+	e.network = true
+	e.order = binary.LittleEndian
+	e.strings = UTF8
+}
+
+// SetStringEncoding sets the encoding of strings. The default is ModifiedUTF8.
+func (e *Encoder) SetStringEncoding(enc StringEncoding) { e.strings = enc }
+
+// UseNamelessRoot makes the Encoder write root tags without a name. See Decoder.UseNamelessRoot.
+func (e *Encoder) UseNamelessRoot() { e.nameless = true }
+
+// SetSortKeys makes the Encoder write the entries of compounds sorted by name, so the output is deterministic.
+func (e *Encoder) SetSortKeys(sortKeys bool) { e.sortKeys = sortKeys }
+
+// SetCompression sets the compression of the output stream. It must be called before the first call to Encode.
+func (e *Encoder) SetCompression(c Compression) { e.comp = c }
+
+func (e *Encoder) start() error {
+	e.started = true
+
+	if !isUncompressed(e.comp) {
+		cw, err := e.comp.NewWriter(e.dst)
+		if err != nil {
+			return err
+		}
+		e.cw = cw
+	}
+	return nil
+}
+
+// encodeBuffers are the buffers root tags are serialized into by Encoders.
+var encodeBuffers = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+// Encode writes a named root tag to the stream. With UseNamelessRoot, name is ignored.
+//
+// If the tag is invalid, nothing is written.
+func (e *Encoder) Encode(name string, tag Tag) error {
+	buf := encodeBuffers.Get().(*[]byte)
+	defer encodeBuffers.Put(buf)
+
+	out, err := e.AppendEncode((*buf)[:0], name, tag)
+	if err != nil {
+		return err
+	}
+	*buf = out
+	return e.write(out)
+}
+
+// EncodeOrdered is like Encode, but writes the entries of compounds in the order given by order (see
+// Decoder.DecodeOrdered). Entries that are not in order follow, sorted by name. order may be nil.
+func (e *Encoder) EncodeOrdered(name string, tag Tag, order *KeyOrder) error {
+	e.keys = order
+	defer func() { e.keys = nil }()
+	return e.Encode(name, tag)
+}
+
+// EncodePayload writes only the payload of tag to the stream, without its type and name. See Decoder.DecodePayload.
+func (e *Encoder) EncodePayload(tag Tag) error {
+	buf := encodeBuffers.Get().(*[]byte)
+	defer encodeBuffers.Put(buf)
+
+	out, err := e.AppendPayload((*buf)[:0], tag)
+	if err != nil {
+		return err
+	}
+	*buf = out
+	return e.write(out)
+}
+
+// AppendEncode appends a named root tag to dst like Encode, using the settings of the Encoder except the compression.
+// It returns the extended buffer, or dst if the tag is invalid. Nothing is written to the stream, so the Encoder may
+// have been created with a nil io.Writer.
+//
+// Once dst has grown large enough, AppendEncode doesn't allocate memory, unless compound keys are sorted (see
+// SetSortKeys) or modified UTF-8 strings are written in the network format.
+func (e *Encoder) AppendEncode(dst []byte, name string, tag Tag) ([]byte, error) {
+	e.path = e.path[:0]
+	var b []byte
+	var err error
+	if e.nameless {
+		b, err = e.appendTagData(append(dst, byte(tag.Type)), tag.Type, tag.Payload)
+	} else {
+		b, err = e.appendNamedTag(dst, name, tag)
+	}
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+// AppendPayload appends the payload of tag to dst like EncodePayload. See AppendEncode.
+func (e *Encoder) AppendPayload(dst []byte, tag Tag) ([]byte, error) {
+	e.path = e.path[:0]
+	b, err := e.appendTagData(dst, tag.Type, tag.Payload)
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+// write writes the data of a complete root tag.
+func (e *Encoder) write(data []byte) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	w := e.dst
+	if e.cw != nil {
+		w = e.cw
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// Close finishes the compressed stream. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	if e.cw != nil {
+		return e.cw.Close()
+	}
+	return nil
+}
+
+// appenders are reused by AppendNamedTag, so the paths don't have to be allocated again.
+var appenders = sync.Pool{New: func() interface{} { return &appender{order: binary.BigEndian} }}
+
+// AppendNamedTag appends a named Tag to dst and returns the extended buffer (or dst on error), like WriteNamedTag.
+// Once dst has grown large enough, it doesn't allocate memory. Use Encoder.AppendEncode for more options.
+func AppendNamedTag(dst []byte, name string, tag Tag) ([]byte, error) {
+	a := appenders.Get().(*appender)
+	defer appenders.Put(a)
+
+	a.path = a.path[:0]
+	b, err := a.appendNamedTag(dst, name, tag)
+	if err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+func (a *appender) appendInt16(b []byte, v int16) []byte {
+	if a.order == binary.BigEndian {
+		return append(b, byte(v>>8), byte(v))
+	}
+	var buf [2]byte
+	a.order.PutUint16(buf[:], uint16(v))
+	return append(b, buf[:]...)
+}
+
+func (a *appender) appendInt32(b []byte, v int32) []byte {
+	if a.order == binary.BigEndian {
+		return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	var buf [4]byte
+	a.order.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
+
+func (a *appender) appendInt64(b []byte, v int64) []byte {
+	if a.order == binary.BigEndian {
+		return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	var buf [8]byte
+	a.order.PutUint64(buf[:], uint64(v))
+	return append(b, buf[:]...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendInt appends the value of a TAG_Int.
+func (a *appender) appendInt(b []byte, v int32) []byte {
+	if !a.network {
+		return a.appendInt32(b, v)
+	}
+	return appendUvarint(b, uint64(uint32(v<<1)^uint32(v>>31)))
+}
+
+// appendLong appends the value of a TAG_Long.
+func (a *appender) appendLong(b []byte, v int64) []byte {
+	if !a.network {
+		return a.appendInt64(b, v)
+	}
+	return appendUvarint(b, uint64(v<<1)^uint64(v>>63))
+}
+
+// appendLength appends the length of an array or list.
+func (a *appender) appendLength(b []byte, l int) ([]byte, error) {
+	if l > math.MaxInt32 {
+		return b, errors.New("Array or list is too long")
+	}
+	return a.appendInt(b, int32(l)), nil
+}
+
+// appendStringLength appends the length of a string. Only the fixed-width length is limited to 16 bits, the network
+// format uses a varint.
+func (a *appender) appendStringLength(b []byte, l int) ([]byte, error) {
+	if a.network {
+		if l > math.MaxInt32 {
+			return b, errors.New("String is too long")
+		}
+		return appendUvarint(b, uint64(l)), nil
+	}
+	if l > math.MaxInt16 {
+		return b, errors.New("String is too long")
+	}
+	return a.appendInt16(b, int16(l)), nil
+}
+
+// grow makes room for n more bytes in b and returns the extended slice and the new part.
+func grow(b []byte, n int) ([]byte, []byte) {
+	l := len(b)
+	if cap(b)-l < n {
+		nb := make([]byte, l, 2*cap(b)+n)
+		copy(nb, b)
+		b = nb
+	}
+	b = b[:l+n]
+	return b, b[l:]
+}
+
+// appendInts appends the elements of a TAG_Int_Array in bulk.
+func (a *appender) appendInts(b []byte, s []int32) []byte {
+	if a.network {
+		for _, v := range s {
+			b = a.appendInt(b, v)
+		}
+		return b
+	}
+
+	b, p := grow(b, 4*len(s))
+	switch a.order {
+	case binary.BigEndian:
+		for i, v := range s {
+			binary.BigEndian.PutUint32(p[4*i:], uint32(v))
+		}
+	case binary.LittleEndian:
+		for i, v := range s {
+			binary.LittleEndian.PutUint32(p[4*i:], uint32(v))
+		}
+	default:
+		for i, v := range s {
+			a.order.PutUint32(p[4*i:], uint32(v))
+		}
+	}
+	return b
+}
+
+// appendLongs appends the elements of a TAG_Long_Array in bulk.
+func (a *appender) appendLongs(b []byte, s []int64) []byte {
+	if a.network {
+		for _, v := range s {
+			b = a.appendLong(b, v)
+		}
+		return b
+	}
+
+	b, p := grow(b, 8*len(s))
+	switch a.order {
+	case binary.BigEndian:
+		for i, v := range s {
+			binary.BigEndian.PutUint64(p[8*i:], uint64(v))
+		}
+	case binary.LittleEndian:
+		for i, v := range s {
+			binary.LittleEndian.PutUint64(p[8*i:], uint64(v))
+		}
+	default:
+		for i, v := range s {
+			a.order.PutUint64(p[8*i:], uint64(v))
+		}
+	}
+	return b
+}
+
+func (a *appender) appendString(b []byte, s string) ([]byte, error) {
+	if isPlainASCII(s) || (a.strings == UTF8 && utf8.ValidString(s)) {
+		b, err := a.appendStringLength(b, len(s))
+		return append(b, s...), err
+	}
+	if a.strings == UTF8 {
+		return b, errInvalidUTF8
+	}
+
+	if a.network {
+		// The length is a varint, so its size is only known after encoding.
+		data, err := encodeMUTF8(s)
+		if err != nil {
+			return b, err
+		}
+		b, err = a.appendStringLength(b, len(data))
+		return append(b, data...), err
+	}
+
+	// Reserve the length and fill it in afterwards.
+	start := len(b)
+	out, err := appendMUTF8(append(b, 0, 0), s)
+	if err != nil {
+		return b, err
+	}
+	l := len(out) - start - 2
+	if l > math.MaxInt16 {
+		return b, errors.New("String is too long")
+	}
+	a.order.PutUint16(out[start:], uint16(l))
+	return out, nil
+}
+
+// mismatch returns the error for a payload that does not fit tt.
+func (a *appender) mismatch(tt TagType, data interface{}) error {
+	return payloadMismatch(a.path.String(), tt, data)
+}
+
+// appendTagData appends the payload of a tag. If the payload does not fit tt, a *ValidationError is returned.
+func (a *appender) appendTagData(b []byte, tt TagType, data interface{}) ([]byte, error) {
+	switch tt {
+	case TAG_End:
+		if data != nil {
+			return b, a.mismatch(tt, data)
+		}
+		return b, nil
+	case TAG_Byte:
+		v, ok := data.(byte)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return append(b, v), nil
+	case TAG_Short:
+		v, ok := data.(int16)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendInt16(b, v), nil
+	case TAG_Int:
+		v, ok := data.(int32)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendInt(b, v), nil
+	case TAG_Long:
+		v, ok := data.(int64)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendLong(b, v), nil
+	case TAG_Float:
+		v, ok := data.(float32)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendInt32(b, int32(math.Float32bits(v))), nil
+	case TAG_Double:
+		v, ok := data.(float64)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendInt64(b, int64(math.Float64bits(v))), nil
+	case TAG_Byte_Array:
+		slice, ok := data.([]byte)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		b, err := a.appendLength(b, len(slice))
+		return append(b, slice...), err
+	case TAG_String:
+		v, ok := data.(string)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		return a.appendString(b, v)
+	case TAG_List:
+		list, ok := data.(TagList)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		if list.Type == TAG_End && len(list.Elems) > 0 {
+			return b, &ValidationError{a.path.String(), tt, "list of TAG_End has elements"}
+		}
+		b, err := a.appendLength(append(b, byte(list.Type)), len(list.Elems))
+		if err != nil {
+			return b, err
+		}
+
+		keys := a.keys
+		for i, el := range list.Elems {
+			a.path = append(a.path, PathElem{Kind: PathIndex, Index: i})
+			a.keys = keys.child(i)
+			b, err = a.appendTagData(b, list.Type, el)
+			a.keys = keys
+			if err != nil {
+				return b, err
+			}
+			a.path = a.path[:len(a.path)-1]
+		}
+		return b, nil
+	case TAG_Compound:
+		comp, ok := data.(TagCompound)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+
+		var err error
+		if keys := a.keys; keys != nil {
+			for i, name := range keys.Names {
+				tag, ok := comp[name]
+				if !ok {
+					continue
+				}
+				a.keys = keys.child(i)
+				b, err = a.appendEntry(b, name, tag)
+				a.keys = keys
+				if err != nil {
+					return b, err
+				}
+			}
+			a.keys = nil
+			for _, name := range keys.extraNames(comp) {
+				if b, err = a.appendEntry(b, name, comp[name]); err != nil {
+					return b, err
+				}
+			}
+			a.keys = keys
+		} else if a.sortKeys {
+			for _, name := range sortedNames(comp) {
+				if b, err = a.appendEntry(b, name, comp[name]); err != nil {
+					return b, err
+				}
+			}
+		} else {
+			for name, tag := range comp {
+				if b, err = a.appendEntry(b, name, tag); err != nil {
+					return b, err
+				}
+			}
+		}
+		return append(b, TAG_End), nil
+	case TAG_Int_Array:
+		slice, ok := data.([]int32)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		b, err := a.appendLength(b, len(slice))
+		if err != nil {
+			return b, err
+		}
+		return a.appendInts(b, slice), nil
+	case TAG_Long_Array:
+		slice, ok := data.([]int64)
+		if !ok {
+			return b, a.mismatch(tt, data)
+		}
+		b, err := a.appendLength(b, len(slice))
+		if err != nil {
+			return b, err
+		}
+		return a.appendLongs(b, slice), nil
+	}
+
+	return b, &ValidationError{a.path.String(), tt, "unknown tag type"}
+}
+
+// appendEntry appends an entry of a compound.
+func (a *appender) appendEntry(b []byte, name string, tag Tag) ([]byte, error) {
+	a.path = append(a.path, PathElem{Name: name})
+	if tag.Type == TAG_End {
+		return b, &ValidationError{a.path.String(), tag.Type, "TAG_End can not be stored in a compound"}
+	}
+	b, err := a.appendNamedTag(b, name, tag)
+	if err != nil {
+		return b, err
+	}
+	a.path = a.path[:len(a.path)-1]
+	return b, nil
+}
+
+func (a *appender) appendNamedTag(b []byte, name string, tag Tag) ([]byte, error) {
+	b, err := a.appendString(append(b, byte(tag.Type)), name)
+	if err != nil {
+		return b, err
+	}
+	return a.appendTagData(b, tag.Type, tag.Payload)
+}
@@ -0,0 +1,136 @@
+package nbt
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+)
+
+// Clone returns a deep copy of t that shares no compounds, lists or arrays with t.
+func (t Tag) Clone() Tag {
+	switch v := t.Payload.(type) {
+	case []byte:
+		if v != nil {
+			return Tag{t.Type, append(make([]byte, 0, len(v)), v...)}
+		}
+	case []int32:
+		if v != nil {
+			return Tag{t.Type, append(make([]int32, 0, len(v)), v...)}
+		}
+	case []int64:
+		if v != nil {
+			return Tag{t.Type, append(make([]int64, 0, len(v)), v...)}
+		}
+	case TagList:
+		if v.Elems == nil {
+			return t
+		}
+		elems := make([]interface{}, len(v.Elems))
+		for i, e := range v.Elems {
+			elems[i] = Tag{v.Type, e}.Clone().Payload
+		}
+		return Tag{t.Type, TagList{v.Type, elems}}
+	case TagCompound:
+		if v == nil {
+			return t
+		}
+		comp := make(TagCompound, len(v))
+		for name, tag := range v {
+			comp[name] = tag.Clone()
+		}
+		return Tag{t.Type, comp}
+	}
+	return t
+}
+
+// Equal reports, if a and b are equal NBT data:
+//
+// Types must be equal, including the element types of lists (even empty ones). Floats are compared bit by bit, so NaN
+// equals NaN with the same bits, but 0.0 does not equal -0.0. Compounds are equal, if they have the same entries,
+// regardless of their order.
+func Equal(a, b Tag) bool {
+	return equal(a, b, -1)
+}
+
+// ApproxEqual is like Equal, but floats are considered equal, if they differ by at most tolerance (or are both NaN).
+func ApproxEqual(a, b Tag, tolerance float64) bool {
+	return equal(a, b, math.Abs(tolerance))
+}
+
+// equal compares a and b. Floats are compared bitwise, if tol is negative.
+func equal(a, b Tag, tol float64) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	switch va := a.Payload.(type) {
+	case float32:
+		vb, ok := b.Payload.(float32)
+		if tol < 0 {
+			return ok && math.Float32bits(va) == math.Float32bits(vb)
+		}
+		return ok && floatsNear(float64(va), float64(vb), tol)
+	case float64:
+		vb, ok := b.Payload.(float64)
+		if tol < 0 {
+			return ok && math.Float64bits(va) == math.Float64bits(vb)
+		}
+		return ok && floatsNear(va, vb, tol)
+	case TagList:
+		vb, ok := b.Payload.(TagList)
+		if !ok || va.Type != vb.Type || len(va.Elems) != len(vb.Elems) {
+			return false
+		}
+		for i := range va.Elems {
+			if !equal(Tag{va.Type, va.Elems[i]}, Tag{vb.Type, vb.Elems[i]}, tol) {
+				return false
+			}
+		}
+		return true
+	case TagCompound:
+		ca := va
+		cb, ok := b.Payload.(TagCompound)
+		if !ok || len(ca) != len(cb) {
+			return false
+		}
+		for name, ta := range ca {
+			if tb, ok := cb[name]; !ok || !equal(ta, tb, tol) {
+				return false
+			}
+		}
+		return true
+	case []byte:
+		vb, ok := b.Payload.([]byte)
+		return ok && bytes.Equal(va, vb)
+	case []int32:
+		vb, ok := b.Payload.([]int32)
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if va[i] != vb[i] {
+				return false
+			}
+		}
+		return true
+	case []int64:
+		vb, ok := b.Payload.([]int64)
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if va[i] != vb[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Payload, b.Payload)
+}
+
+func floatsNear(a, b, tol float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return a == b || math.Abs(a-b) <= tol
+}
@@ -0,0 +1,88 @@
+package nbt
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	orig, _, err := ReadGzipdNamedTag(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not read bigtest: %s", err)
+	}
+	clone := orig.Clone()
+	if !Equal(orig, clone) {
+		t.Fatalf("Clone is not equal to the original")
+	}
+
+	comp := clone.Payload.(TagCompound)
+	comp["new"] = NewIntTag(1)
+	comp["byteArrayTest (the first 1000 values of (n*n*255+n*7)%100, starting with n=0 (0, 62, 34, 16, 8, ...))"].Payload.([]byte)[0] = 99
+	l, _ := comp.GetList("listTest (compound)")
+	l.Elems[0].(TagCompound)["name"] = NewStringTag("changed")
+
+	if _, ok := orig.Payload.(TagCompound)["new"]; ok {
+		t.Errorf("Adding to the clone changed the original")
+	}
+	if !Equal(orig, func() Tag { o, _, _ := ReadGzipdNamedTag(bytes.NewReader(bigtest())); return o }()) {
+		t.Errorf("Changing the clone changed the original")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	nan := math.Float32frombits(0x7fc00000)
+	tenth := 0.1 // A variable, so 0.1 + 0.2 is not computed exactly at compile time.
+	tests := []struct {
+		a, b  Tag
+		equal bool
+	}{
+		{NewIntTag(1), NewIntTag(1), true},
+		{NewIntTag(1), NewLongTag(1), false},
+		{NewFloatTag(nan), NewFloatTag(nan), true},
+		{NewFloatTag(0), NewFloatTag(float32(math.Copysign(0, -1))), false},
+		{NewDoubleTag(tenth + 0.2), NewDoubleTag(0.3), false},
+		{Tag{TAG_List, TagList{TAG_Int, []interface{}{}}}, Tag{TAG_List, TagList{TAG_Short, []interface{}{}}}, false},
+		{NewListTag(TAG_Int, []int32{1, 2}), NewListTag(TAG_Int, []int32{1, 2}), true},
+		{NewListTag(TAG_Int, []int32{1, 2}), NewListTag(TAG_Int, []int32{2, 1}), false},
+		{NewByteArrayTag(nil), NewByteArrayTag([]byte{}), true},
+		{NewIntArrayTag([]int32{1}), NewListTag(TAG_Int, []int32{1}), false},
+		{
+			Tag{TAG_Compound, TagCompound{"a": NewIntTag(1), "b": NewStringTag("x")}},
+			Tag{TAG_Compound, TagCompound{"b": NewStringTag("x"), "a": NewIntTag(1)}},
+			true,
+		},
+		{
+			Tag{TAG_Compound, TagCompound{"a": NewIntTag(1)}},
+			Tag{TAG_Compound, TagCompound{"a": NewIntTag(1), "b": NewIntTag(2)}},
+			false,
+		},
+	}
+
+	for i, test := range tests {
+		if Equal(test.a, test.b) != test.equal || Equal(test.b, test.a) != test.equal {
+			t.Errorf("Test %d: Equal(%s, %s) should be %t", i, test.a, test.b, test.equal)
+		}
+	}
+}
+
+func TestApproxEqual(t *testing.T) {
+	tenth := 0.1
+	a := Tag{TAG_Compound, TagCompound{"pos": NewListTag(TAG_Double, []float64{tenth + 0.2, 64}), "f": NewFloatTag(1.5)}}
+	b := Tag{TAG_Compound, TagCompound{"pos": NewListTag(TAG_Double, []float64{0.3, 64}), "f": NewFloatTag(1.5001)}}
+	if Equal(a, b) {
+		t.Errorf("Equal should compare exactly")
+	}
+	if !ApproxEqual(a, b, 0.001) {
+		t.Errorf("ApproxEqual should accept small differences")
+	}
+	if ApproxEqual(a, b, 1e-6) {
+		t.Errorf("ApproxEqual should reject differences above the tolerance")
+	}
+	if !ApproxEqual(NewDoubleTag(math.NaN()), NewDoubleTag(-math.NaN()), 0) {
+		t.Errorf("ApproxEqual should consider all NaNs equal")
+	}
+	if ApproxEqual(NewDoubleTag(math.NaN()), NewDoubleTag(1), math.Inf(1)) {
+		t.Errorf("NaN should not equal a number")
+	}
+}
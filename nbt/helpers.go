@@ -120,3 +120,13 @@ func (tc TagCompound) GetIntArray(key string) ([]int32, error) {
 	}
 	return t.Payload.([]int32), nil
 }
+func (tc TagCompound) GetLongArray(key string) ([]int64, error) {
+	t, ok := tc[key]
+	if !ok {
+		return nil, NotFound
+	}
+	if t.Type != TAG_Long_Array {
+		return nil, WrongType
+	}
+	return t.Payload.([]int64), nil
+}
@@ -0,0 +1,511 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// JSONMode selects the JSON representation of NBT data.
+type JSONMode int
+
+// Valid JSONMode values.
+const (
+	// TypedJSON represents every tag as an object {"type": "TAG_...", "value": ...}, so the round trip is exact.
+	// The value depends on the type:
+	//
+	//	TAG_Byte, TAG_Short, TAG_Int   -- number (bytes are signed)
+	//	TAG_Long                       -- string, since JSON numbers often can't hold 64 bit integers exactly
+	//	TAG_Float, TAG_Double          -- number, or one of the strings "NaN", "Infinity", "-Infinity"
+	//	TAG_Byte_Array                 -- base64 encoded string
+	//	TAG_String                     -- string
+	//	TAG_List                       -- {"elemType": "TAG_...", "elems": [...]}, elems being values of elemType
+	//	TAG_Compound                   -- object of typed tags
+	//	TAG_Int_Array                  -- array of numbers
+	//	TAG_Long_Array                 -- array of strings
+	TypedJSON JSONMode = iota
+
+	// PlainJSON represents tags as natural JSON values for display: numbers, strings, arrays and objects. Longs beyond
+	// ±2^53 are written as strings. Types are lost, when reading plain JSON (see FromJSON).
+	PlainJSON
+)
+
+// JSONOptions control ToJSON and FromJSON.
+type JSONOptions struct {
+	Mode   JSONMode
+	Indent string // If not empty, ToJSON writes one value per line, indented by Indent per level.
+}
+
+// JSONError is returned by FromJSON, if the JSON data can't be converted to a Tag.
+type JSONError struct {
+	Path string // Path of the tag.
+	Msg  string
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("nbt: json: %s: %s", pathOrRoot(e.Path), e.Msg)
+}
+
+// maxSafeJSONInt is the largest integer, that JavaScript can represent exactly.
+const maxSafeJSONInt = 1<<53 - 1
+
+// ToJSON converts tag into JSON. Compound entries are written sorted by name.
+func ToJSON(tag Tag, opts JSONOptions) ([]byte, error) {
+	if err := tag.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	if opts.Mode == PlainJSON {
+		writePlainJSON(buf, tag)
+	} else {
+		writeTypedJSON(buf, tag)
+	}
+
+	if opts.Indent == "" {
+		return buf.Bytes(), nil
+	}
+	out := new(bytes.Buffer)
+	if err := json.Indent(out, buf.Bytes(), "", opts.Indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
+
+func writeJSONFloat(buf *bytes.Buffer, v float64, bits int) {
+	switch {
+	case math.IsNaN(v):
+		buf.WriteString(`"NaN"`)
+	case math.IsInf(v, 1):
+		buf.WriteString(`"Infinity"`)
+	case math.IsInf(v, -1):
+		buf.WriteString(`"-Infinity"`)
+	default:
+		buf.WriteString(strconv.FormatFloat(v, 'g', -1, bits))
+	}
+}
+
+// compoundEntries calls fn for the entries of a compound, sorted by name.
+func compoundEntries(payload interface{}, fn func(name string, tag Tag)) {
+	comp := payload.(TagCompound)
+	for _, name := range sortedNames(comp) {
+		fn(name, comp[name])
+	}
+}
+
+func writeTypedJSON(buf *bytes.Buffer, tag Tag) {
+	buf.WriteString(`{"type":"` + tag.Type.String() + `","value":`)
+	writeTypedPayload(buf, tag.Type, tag.Payload)
+	buf.WriteByte('}')
+}
+
+func writeTypedPayload(buf *bytes.Buffer, tt TagType, data interface{}) {
+	switch tt {
+	case TAG_End:
+		buf.WriteString("null")
+	case TAG_Byte:
+		buf.WriteString(strconv.Itoa(int(int8(data.(byte)))))
+	case TAG_Short:
+		buf.WriteString(strconv.Itoa(int(data.(int16))))
+	case TAG_Int:
+		buf.WriteString(strconv.Itoa(int(data.(int32))))
+	case TAG_Long:
+		buf.WriteString(`"` + strconv.FormatInt(data.(int64), 10) + `"`)
+	case TAG_Float:
+		writeJSONFloat(buf, float64(data.(float32)), 32)
+	case TAG_Double:
+		writeJSONFloat(buf, data.(float64), 64)
+	case TAG_Byte_Array:
+		buf.WriteString(`"` + base64.StdEncoding.EncodeToString(data.([]byte)) + `"`)
+	case TAG_String:
+		writeJSONString(buf, data.(string))
+	case TAG_List:
+		l := data.(TagList)
+		buf.WriteString(`{"elemType":"` + l.Type.String() + `","elems":[`)
+		for i, e := range l.Elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeTypedPayload(buf, l.Type, e)
+		}
+		buf.WriteString("]}")
+	case TAG_Compound:
+		buf.WriteByte('{')
+		first := true
+		compoundEntries(data, func(name string, tag Tag) {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			writeTypedJSON(buf, tag)
+		})
+		buf.WriteByte('}')
+	case TAG_Int_Array:
+		buf.WriteByte('[')
+		for i, v := range data.([]int32) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Itoa(int(v)))
+		}
+		buf.WriteByte(']')
+	case TAG_Long_Array:
+		buf.WriteByte('[')
+		for i, v := range data.([]int64) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`"` + strconv.FormatInt(v, 10) + `"`)
+		}
+		buf.WriteByte(']')
+	}
+}
+
+func writePlainLong(buf *bytes.Buffer, v int64) {
+	if v > maxSafeJSONInt || v < -maxSafeJSONInt {
+		buf.WriteString(`"` + strconv.FormatInt(v, 10) + `"`)
+	} else {
+		buf.WriteString(strconv.FormatInt(v, 10))
+	}
+}
+
+func writePlainJSON(buf *bytes.Buffer, tag Tag) {
+	switch tag.Type {
+	case TAG_Long:
+		writePlainLong(buf, tag.Payload.(int64))
+	case TAG_Byte_Array:
+		buf.WriteByte('[')
+		for i, v := range tag.Payload.([]byte) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Itoa(int(int8(v))))
+		}
+		buf.WriteByte(']')
+	case TAG_List:
+		l := tag.Payload.(TagList)
+		buf.WriteByte('[')
+		for i, e := range l.Elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writePlainJSON(buf, Tag{l.Type, e})
+		}
+		buf.WriteByte(']')
+	case TAG_Compound:
+		buf.WriteByte('{')
+		first := true
+		compoundEntries(tag.Payload, func(name string, tag Tag) {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			writePlainJSON(buf, tag)
+		})
+		buf.WriteByte('}')
+	case TAG_Long_Array:
+		buf.WriteByte('[')
+		for i, v := range tag.Payload.([]int64) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writePlainLong(buf, v)
+		}
+		buf.WriteByte(']')
+	default:
+		writeTypedPayload(buf, tag.Type, tag.Payload)
+	}
+}
+
+// FromJSON converts JSON data into a Tag.
+//
+// For TypedJSON, the data must be in the format written by ToJSON. For PlainJSON, the types are guessed: objects
+// become TAG_Compounds, arrays TAG_Lists, strings TAG_Strings and booleans TAG_Bytes. Integers become TAG_Ints or, if
+// they don't fit, TAG_Longs; other numbers TAG_Doubles. The elements of an array must have the same type, except for
+// numbers, which are converted to the widest type among them. An empty array becomes a list of TAG_End.
+func FromJSON(data []byte, opts JSONOptions) (Tag, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return Tag{}, err
+	}
+	if dec.More() {
+		return Tag{}, &JSONError{"", "unexpected data after the value"}
+	}
+
+	if opts.Mode == PlainJSON {
+		return plainFromJSON(v, Path{})
+	}
+	return typedFromJSON(v, Path{})
+}
+
+func tagTypeByName(name string) (TagType, bool) {
+	for tt := TagType(TAG_End); tt <= TAG_Long_Array; tt++ {
+		if tt.String() == name {
+			return tt, true
+		}
+	}
+	return 0, false
+}
+
+func typedFromJSON(v interface{}, path Path) (Tag, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return Tag{}, &JSONError{path.String(), "expected {\"type\": ..., \"value\": ...}"}
+	}
+	name, _ := obj["type"].(string)
+	tt, ok := tagTypeByName(name)
+	if !ok {
+		return Tag{}, &JSONError{path.String(), fmt.Sprintf("invalid type %#v", obj["type"])}
+	}
+
+	payload, err := typedPayloadFromJSON(tt, obj["value"], path)
+	return Tag{tt, payload}, err
+}
+
+func jsonInt(v interface{}, path Path, min, max int64) (int64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, &JSONError{path.String(), fmt.Sprintf("expected a number, have %#v", v)}
+	}
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil || i < min || i > max {
+		return 0, &JSONError{path.String(), fmt.Sprintf("expected an integer in [%d, %d], have %s", min, max, n)}
+	}
+	return i, nil
+}
+
+func jsonLong(v interface{}, path Path) (int64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return jsonInt(v, path, math.MinInt64, math.MaxInt64)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, &JSONError{path.String(), fmt.Sprintf("invalid long %#v", s)}
+	}
+	return i, nil
+}
+
+func jsonFloat(v interface{}, path Path, bits int) (float64, error) {
+	switch f := v.(type) {
+	case json.Number:
+		if x, err := strconv.ParseFloat(string(f), bits); err == nil {
+			return x, nil
+		}
+	case string:
+		switch f {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+	}
+	return 0, &JSONError{path.String(), fmt.Sprintf("invalid float %#v", v)}
+}
+
+func jsonArray(v interface{}, path Path) ([]interface{}, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil, &JSONError{path.String(), fmt.Sprintf("expected an array, have %#v", v)}
+	}
+	return a, nil
+}
+
+func typedPayloadFromJSON(tt TagType, v interface{}, path Path) (interface{}, error) {
+	switch tt {
+	case TAG_End:
+		if v != nil {
+			return nil, &JSONError{path.String(), "TAG_End must have the value null"}
+		}
+		return nil, nil
+	case TAG_Byte:
+		i, err := jsonInt(v, path, math.MinInt8, math.MaxInt8)
+		return byte(i), err
+	case TAG_Short:
+		i, err := jsonInt(v, path, math.MinInt16, math.MaxInt16)
+		return int16(i), err
+	case TAG_Int:
+		i, err := jsonInt(v, path, math.MinInt32, math.MaxInt32)
+		return int32(i), err
+	case TAG_Long:
+		return jsonLong(v, path)
+	case TAG_Float:
+		f, err := jsonFloat(v, path, 32)
+		return float32(f), err
+	case TAG_Double:
+		return jsonFloat(v, path, 64)
+	case TAG_Byte_Array:
+		s, ok := v.(string)
+		if !ok {
+			return nil, &JSONError{path.String(), "expected a base64 string"}
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, &JSONError{path.String(), err.Error()}
+		}
+		return data, nil
+	case TAG_String:
+		s, ok := v.(string)
+		if !ok {
+			return nil, &JSONError{path.String(), fmt.Sprintf("expected a string, have %#v", v)}
+		}
+		return s, nil
+	case TAG_List:
+		obj, _ := v.(map[string]interface{})
+		name, _ := obj["elemType"].(string)
+		et, ok := tagTypeByName(name)
+		if !ok {
+			return nil, &JSONError{path.String(), fmt.Sprintf("invalid element type %#v", obj["elemType"])}
+		}
+		elems, err := jsonArray(obj["elems"], path)
+		if err != nil {
+			return nil, err
+		}
+		l := TagList{et, make([]interface{}, len(elems))}
+		for i, e := range elems {
+			if l.Elems[i], err = typedPayloadFromJSON(et, e, path.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case TAG_Compound:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, &JSONError{path.String(), "expected an object"}
+		}
+		comp := make(TagCompound, len(obj))
+		for name, e := range obj {
+			tag, err := typedFromJSON(e, path.Key(name))
+			if err != nil {
+				return nil, err
+			}
+			comp[name] = tag
+		}
+		return comp, nil
+	case TAG_Int_Array:
+		a, err := jsonArray(v, path)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]int32, len(a))
+		for i, e := range a {
+			n, err := jsonInt(e, path.Index(i), math.MinInt32, math.MaxInt32)
+			if err != nil {
+				return nil, err
+			}
+			data[i] = int32(n)
+		}
+		return data, nil
+	case TAG_Long_Array:
+		a, err := jsonArray(v, path)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]int64, len(a))
+		for i, e := range a {
+			if data[i], err = jsonLong(e, path.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+	return nil, &JSONError{path.String(), "unknown tag type"}
+}
+
+func plainFromJSON(v interface{}, path Path) (Tag, error) {
+	switch x := v.(type) {
+	case bool:
+		if x {
+			return NewByteTag(1), nil
+		}
+		return NewByteTag(0), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return NewIntTag(int32(i)), nil
+			}
+			return NewLongTag(i), nil
+		}
+		f, err := strconv.ParseFloat(string(x), 64)
+		if err != nil {
+			return Tag{}, &JSONError{path.String(), fmt.Sprintf("number %s out of range", x)}
+		}
+		return NewDoubleTag(f), nil
+	case string:
+		return NewStringTag(x), nil
+	case []interface{}:
+		l := TagList{TAG_End, make([]interface{}, len(x))}
+		tags := make([]Tag, len(x))
+		for i, e := range x {
+			tag, err := plainFromJSON(e, path.Index(i))
+			if err != nil {
+				return Tag{}, err
+			}
+			tags[i] = tag
+			if i == 0 || l.Type == tag.Type {
+				l.Type = tag.Type
+			} else if isPlainNumber(l.Type) && isPlainNumber(tag.Type) {
+				if tag.Type > l.Type {
+					l.Type = tag.Type
+				}
+			} else {
+				return Tag{}, &JSONError{path.String(), fmt.Sprintf("array mixes %s and %s", l.Type, tag.Type)}
+			}
+		}
+		for i, tag := range tags {
+			l.Elems[i] = convertPlainNumber(tag, l.Type).Payload
+		}
+		return Tag{TAG_List, l}, nil
+	case map[string]interface{}:
+		comp := make(TagCompound, len(x))
+		for name, e := range x {
+			tag, err := plainFromJSON(e, path.Key(name))
+			if err != nil {
+				return Tag{}, err
+			}
+			comp[name] = tag
+		}
+		return Tag{TAG_Compound, comp}, nil
+	}
+	return Tag{}, &JSONError{path.String(), fmt.Sprintf("can not convert %#v", v)}
+}
+
+// isPlainNumber checks, if tt is one of the number types used for plain JSON. Their TagType order is also their width order.
+func isPlainNumber(tt TagType) bool {
+	return tt == TAG_Int || tt == TAG_Long || tt == TAG_Double
+}
+
+func convertPlainNumber(tag Tag, tt TagType) Tag {
+	if tag.Type == tt {
+		return tag
+	}
+	var f float64
+	switch v := tag.Payload.(type) {
+	case int32:
+		if tt == TAG_Long {
+			return NewLongTag(int64(v))
+		}
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	}
+	return NewDoubleTag(f)
+}
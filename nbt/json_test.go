@@ -0,0 +1,129 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestTypedJSONRoundtrip(t *testing.T) {
+	orig, _, err := ReadGzipdNamedTag(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not read bigtest: %s", err)
+	}
+	comp := orig.Payload.(TagCompound)
+	comp["longs"] = NewLongArrayTag([]int64{math.MinInt64, math.MaxInt64, 1<<53 + 1})
+	comp["floats"] = NewListTag(TAG_Float, []float32{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1)), 0.1})
+	comp["bytes"] = NewByteArrayTag([]byte{0, 127, 128, 255})
+	comp["ints"] = NewIntArrayTag([]int32{math.MinInt32, math.MaxInt32})
+	comp["empty"] = Tag{TAG_List, TagList{TAG_Short, []interface{}{}}}
+	comp["<html> & \"quotes\""] = NewStringTag("ä\x00\n")
+
+	for _, indent := range []string{"", "  "} {
+		data, err := ToJSON(orig, JSONOptions{Mode: TypedJSON, Indent: indent})
+		if err != nil {
+			t.Fatalf("ToJSON failed: %s", err)
+		}
+		back, err := FromJSON(data, JSONOptions{Mode: TypedJSON})
+		if err != nil {
+			t.Fatalf("FromJSON failed: %s\n%s", err, data)
+		}
+		if !Equal(orig, back) {
+			t.Errorf("Round trip changed the tag:\n%s", FormatPatch(Diff(orig, back)))
+		}
+	}
+}
+
+func TestTypedJSONFormat(t *testing.T) {
+	tag := Tag{TAG_Compound, TagCompound{
+		"b":  NewByteTag(255),
+		"a":  NewLongTag(math.MaxInt64),
+		"l":  NewListTag(TAG_Double, []float64{math.NaN()}),
+		"ba": NewByteArrayTag([]byte{1, 2, 3}),
+	}}
+	want := `{"type":"TAG_Compound","value":{` +
+		`"a":{"type":"TAG_Long","value":"9223372036854775807"},` +
+		`"b":{"type":"TAG_Byte","value":-1},` +
+		`"ba":{"type":"TAG_Byte_Array","value":"AQID"},` +
+		`"l":{"type":"TAG_List","value":{"elemType":"TAG_Double","elems":["NaN"]}}}}`
+
+	data, err := ToJSON(tag, JSONOptions{})
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	if string(data) != want {
+		t.Errorf("Want\n%s\nHave\n%s", want, data)
+	}
+}
+
+func TestPlainJSON(t *testing.T) {
+	tag := mustSNBT(t, `{name:"Steve",health:20.5f,xp:5,big:9007199254740993L,small:3L,flag:1b,bytes:[B;1,-1],pos:[1.0d,2.5d]}`)
+	want := `{"big":"9007199254740993","bytes":[1,-1],"flag":1,"health":20.5,"name":"Steve","pos":[1,2.5],"small":3,"xp":5}`
+
+	data, err := ToJSON(tag, JSONOptions{Mode: PlainJSON})
+	if err != nil {
+		t.Fatalf("ToJSON failed: %s", err)
+	}
+	if string(data) != want {
+		t.Errorf("Want\n%s\nHave\n%s", want, data)
+	}
+}
+
+func TestPlainJSONInference(t *testing.T) {
+	tests := []struct {
+		json, snbt string
+	}{
+		{`1`, `1`},
+		{`3000000000`, `3000000000L`},
+		{`1.5`, `1.5d`},
+		{`true`, `1b`},
+		{`"x"`, `"x"`},
+		{`[]`, `[]`},
+		{`[1,2]`, `[1,2]`},
+		{`[1,3000000000]`, `[1L,3000000000L]`},
+		{`[1,2.5]`, `[1.0d,2.5d]`},
+		{`{"a":{"b":[{"c":false}]}}`, `{a:{b:[{c:0b}]}}`},
+	}
+
+	for _, test := range tests {
+		have, err := FromJSON([]byte(test.json), JSONOptions{Mode: PlainJSON})
+		if err != nil {
+			t.Errorf("%s: %s", test.json, err)
+			continue
+		}
+		if want := mustSNBT(t, test.snbt); !Equal(have, want) {
+			t.Errorf("%s: Want %s, have %s", test.json, FormatSNBT(want, SNBTOptions{}), FormatSNBT(have, SNBTOptions{}))
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		mode JSONMode
+		path string
+	}{
+		{`{"a":[1,"x"]}`, PlainJSON, "a"},
+		{`{"a":null}`, PlainJSON, "a"},
+		{`{"type":"TAG_Byte","value":128}`, TypedJSON, ""},
+		{`{"type":"TAG_Compound","value":{"x":{"type":"TAG_Long","value":"1.5"}}}`, TypedJSON, "x"},
+		{`{"type":"TAG_List","value":{"elemType":"TAG_Int","elems":[1,"a"]}}`, TypedJSON, "[1]"},
+		{`{"type":"TAG_Nope","value":1}`, TypedJSON, ""},
+		{`{"type":"TAG_Int","value":1} 2`, TypedJSON, ""},
+	}
+
+	for _, test := range tests {
+		_, err := FromJSON([]byte(test.json), JSONOptions{Mode: test.mode})
+		var je *JSONError
+		if !errors.As(err, &je) {
+			t.Errorf("%s: Want JSONError, have %v", test.json, err)
+		} else if je.Path != test.path {
+			t.Errorf("%s: Want path %q, have %q", test.json, test.path, je.Path)
+		}
+	}
+
+	if _, err := FromJSON([]byte(`{`), JSONOptions{}); err == nil {
+		t.Errorf("Invalid JSON should fail")
+	}
+}
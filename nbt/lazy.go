@@ -0,0 +1,148 @@
+package nbt
+
+import "sync"
+
+// LazyTag is a tag that keeps its raw payload and decodes it on first access. Entries and elements can be accessed as
+// LazyTags without decoding the rest of the data, so a few values of large data are cheap to get.
+//
+// LazyTags are returned by Decoder.DecodeLazy. They share the raw data with the LazyTag they were taken from.
+type LazyTag struct {
+	Type TagType
+
+	raw    []byte
+	format format
+
+	once sync.Once
+	tag  Tag
+	err  error
+}
+
+// DecodeLazy reads the next root tag from the stream without decoding it (see Decode). It returns the LazyTag and the
+// name of the root tag. The settings of the Decoder are used to decode the LazyTag later.
+func (d *Decoder) DecodeLazy() (*LazyTag, string, error) {
+	t := &d.t
+	if len(t.stack) > 0 {
+		t.abort()
+	}
+	if err := t.skipArray(); err != nil {
+		return nil, "", err
+	}
+	if err := t.prepare(); err != nil {
+		return nil, "", t.fail(TAG_End, err)
+	}
+
+	var tt TagType
+	var name string
+	var err error
+	if t.nameless {
+		var _tt byte
+		_tt, err = t.readByte()
+		tt = TagType(_tt)
+	} else {
+		tt, name, err = t.readTagHeader()
+	}
+	if err != nil {
+		return nil, "", t.fail(tt, err)
+	}
+
+	t.recording, t.rec = true, nil
+	err = t.skipPayload(tt, 0)
+	raw := t.rec
+	t.recording, t.rec = false, nil
+	if err != nil {
+		return nil, "", t.fail(tt, err)
+	}
+	return &LazyTag{Type: tt, raw: raw, format: t.format}, name, nil
+}
+
+// Raw returns the raw payload of the tag, as stored in the stream.
+func (l *LazyTag) Raw() []byte { return l.raw }
+
+func (l *LazyTag) decoder() *Decoder {
+	d := NewBytesDecoder(l.raw)
+	d.t.format = l.format
+	return d
+}
+
+// Tag decodes the tag. The result is cached, so the tag is only decoded once.
+func (l *LazyTag) Tag() (Tag, error) {
+	l.once.Do(func() {
+		l.tag, l.err = l.decoder().DecodePayload(l.Type)
+	})
+	return l.tag, l.err
+}
+
+// Entry returns the entry name of a compound. Other entries are skipped without decoding them.
+// WrongType is returned, if the tag is not a compound, NotFound, if the entry does not exist.
+func (l *LazyTag) Entry(name string) (*LazyTag, error) {
+	if l.Type != TAG_Compound {
+		return nil, WrongType
+	}
+
+	t := &l.decoder().t
+	if err := t.prepare(); err != nil {
+		return nil, err
+	}
+	for {
+		tt, raw, err := t.readRawHeader()
+		if err != nil {
+			return nil, t.fail(TAG_Compound, err)
+		}
+		if tt == TAG_End {
+			return nil, NotFound
+		}
+
+		found := t.rawNameEquals(raw, name)
+		start := t.off
+		if err := t.skipPayload(tt, 1); err != nil {
+			return nil, t.fail(TAG_Compound, err)
+		}
+		if found {
+			return l.sub(tt, start, t.off), nil
+		}
+	}
+}
+
+// Elem returns the element i of a list. Other elements are skipped without decoding them.
+// WrongType is returned, if the tag is not a list, NotFound, if i is out of range.
+func (l *LazyTag) Elem(i int) (*LazyTag, error) {
+	if l.Type != TAG_List {
+		return nil, WrongType
+	}
+
+	t := &l.decoder().t
+	if err := t.prepare(); err != nil {
+		return nil, err
+	}
+	ltt, n, err := t.readListHeader()
+	if err != nil {
+		return nil, t.fail(TAG_List, err)
+	}
+	if i < 0 || i >= n {
+		return nil, NotFound
+	}
+
+	if err := t.skipElems(ltt, i, 1); err != nil {
+		return nil, t.fail(TAG_List, err)
+	}
+	start := t.off
+	if err := t.skipPayload(ltt, 1); err != nil {
+		return nil, t.fail(TAG_List, err)
+	}
+	return l.sub(ltt, start, t.off), nil
+}
+
+// sub returns a LazyTag for the payload at raw[start:end].
+func (l *LazyTag) sub(tt TagType, start, end int64) *LazyTag {
+	return &LazyTag{Type: tt, raw: l.raw[start:end:end], format: l.format}
+}
+
+// DecodePaths decodes only the tags matching one of paths. See Decoder.DecodePaths.
+func (l *LazyTag) DecodePaths(paths ...Path) ([]PathMatch, error) {
+	d := l.decoder()
+	tok, err := d.t.NextPayload(l.Type)
+	if err != nil {
+		return nil, err
+	}
+	return d.selectRoot(tok, paths)
+}
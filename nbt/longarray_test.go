@@ -0,0 +1,85 @@
+package nbt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func longArrayTestSeries(n int) int64 { return int64(n*n*255+n*7) << 24 }
+
+func TestLongArrayRoundtrip(t *testing.T) {
+	data := make([]int64, 256)
+	for n := range data {
+		data[n] = longArrayTestSeries(n)
+	}
+	data[0] = -9223372036854775808
+	data[1] = 9223372036854775807
+
+	rootcomp := make(TagCompound)
+	rootcomp["BlockStates"] = NewLongArrayTag(data)
+	rootcomp["empty"] = NewLongArrayTag([]int64{})
+	rootcomp["listTest (long array)"] = NewListTag(TAG_Long_Array, [][]int64{{1, 2}, {-3}})
+
+	buf := new(bytes.Buffer)
+	if err := WriteGzipdNamedTag(buf, "Level", Tag{TAG_Compound, rootcomp}); err != nil {
+		t.Fatalf("Could not write NBT data: %s", err)
+	}
+
+	root, name, err := ReadGzipdNamedTag(buf)
+	if err != nil {
+		t.Fatalf("Could not read NBT data: %s", err)
+	}
+	if name != "Level" {
+		t.Errorf("Wrong name. Want \"Level\", have %#v", name)
+	}
+	if root.Type != TAG_Compound {
+		t.Fatalf("Root tag must be a TAG_Compound, have %s", root.Type)
+	}
+	comp := root.Payload.(TagCompound)
+
+	have, err := comp.GetLongArray("BlockStates")
+	if err != nil {
+		t.Fatalf("Could not get BlockStates: %s", err)
+	}
+	if len(have) != len(data) {
+		t.Fatalf("BlockStates has length %d, expected %d", len(have), len(data))
+	}
+	for i, want := range data {
+		if have[i] != want {
+			t.Errorf("Wrong BlockStates data at index %d: %d, expected %d", i, have[i], want)
+			break
+		}
+	}
+
+	if empty, err := comp.GetLongArray("empty"); err != nil {
+		t.Errorf("Could not get empty: %s", err)
+	} else if len(empty) != 0 {
+		t.Errorf("empty has length %d, expected 0", len(empty))
+	}
+
+	if _, err := comp.GetIntArray("BlockStates"); err != WrongType {
+		t.Errorf("GetIntArray on a TAG_Long_Array: want WrongType, have %v", err)
+	}
+
+	if tag, ok := getKey(t, comp, "listTest (long array)", TAG_List); ok {
+		l := tag.Payload.(TagList)
+		if l.Type != TAG_Long_Array || len(l.Elems) != 2 {
+			t.Fatalf("\"listTest (long array)\" is %s list of length %d, expected TAG_Long_Array list of length 2", l.Type, len(l.Elems))
+		}
+		if a := l.Elems[0].([]int64); len(a) != 2 || a[0] != 1 || a[1] != 2 {
+			t.Errorf("listTest (long array) [0] = %v, expected [1 2]", a)
+		}
+		if a := l.Elems[1].([]int64); len(a) != 1 || a[0] != -3 {
+			t.Errorf("listTest (long array) [1] = %v, expected [-3]", a)
+		}
+	}
+}
+
+func TestLongArrayString(t *testing.T) {
+	if s := TagType(TAG_Long_Array).String(); s != "TAG_Long_Array" {
+		t.Errorf("Wrong TagType name. Want \"TAG_Long_Array\", have %#v", s)
+	}
+	if s := NewLongArrayTag([]int64{1, -2, 3}).String(); s != "TAG_Long_Array: 1, -2, 3" {
+		t.Errorf("Wrong string representation: %#v", s)
+	}
+}
@@ -0,0 +1,404 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// LZ4 support. Minecraft writes LZ4 compressed chunks with the LZ4BlockOutputStream of lz4-java, which does not use the
+// LZ4 frame format, but a stream of blocks, each with this header (numbers are little-endian):
+//
+//	magic     [8]byte -- "LZ4Block"
+//	token     byte    -- method (0x10 raw, 0x20 LZ4) | log2(maximum block size) - 10
+//	compLen   int32   -- Length of the (compressed) data following the header.
+//	origLen   int32   -- Length of the uncompressed data.
+//	checksum  int32   -- XXH32 of the uncompressed data with seed 0x9747b28c, masked to 28 bits.
+//
+// The stream ends with a raw block of length 0 and checksum 0. The data of LZ4 blocks is in the LZ4 block format.
+
+const (
+	lz4Magic        = "LZ4Block"
+	lz4HeaderLen    = len(lz4Magic) + 13
+	lz4MethodRaw    = 0x10
+	lz4MethodLZ4    = 0x20
+	lz4LevelBase    = 10
+	lz4BlockSizeLog = 16 // 64 KiB, the default of lz4-java.
+	lz4Seed         = 0x9747b28c
+	lz4ChecksumMask = 0x0fffffff
+
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5  // The last 5 bytes of a block are always literals.
+	lz4MFLimit      = 12 // The last match must start at least 12 bytes before the end of a block.
+	lz4MaxOffset    = 65535
+	lz4HashLog      = 14
+)
+
+var errLZ4Corrupt = errors.New("nbt: corrupt LZ4 data")
+
+type lz4Compression struct{}
+
+func (lz4Compression) ID() byte                                 { return LZ4ID }
+func (lz4Compression) String() string                           { return "lz4" }
+func (lz4Compression) NewReader(r io.Reader) (io.Reader, error) { return &lz4Reader{r: r}, nil }
+func (lz4Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return &lz4Writer{w: w, buf: make([]byte, 0, 1<<lz4BlockSizeLog)}, nil
+}
+
+type lz4Writer struct {
+	w      io.Writer
+	buf    []byte // Uncompressed data of the current block.
+	out    []byte
+	closed bool
+}
+
+func (lw *lz4Writer) Write(p []byte) (int, error) {
+	if lw.closed {
+		return 0, errors.New("nbt: write to closed LZ4 writer")
+	}
+
+	n := 0
+	for len(p) > 0 {
+		k := copy(lw.buf[len(lw.buf):cap(lw.buf)], p)
+		lw.buf = lw.buf[:len(lw.buf)+k]
+		p = p[k:]
+		n += k
+		if len(lw.buf) == cap(lw.buf) {
+			if err := lw.writeBlock(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (lw *lz4Writer) writeBlock() error {
+	if len(lw.buf) == 0 {
+		return nil
+	}
+
+	lw.out = append(lw.out[:0], make([]byte, lz4HeaderLen)...)
+	lw.out = lz4CompressBlock(lw.out, lw.buf)
+	method := byte(lz4MethodLZ4)
+	if len(lw.out)-lz4HeaderLen >= len(lw.buf) {
+		method = lz4MethodRaw
+		lw.out = append(lw.out[:lz4HeaderLen], lw.buf...)
+	}
+	lz4PutHeader(lw.out, method, len(lw.out)-lz4HeaderLen, len(lw.buf), xxh32(lw.buf, lz4Seed)&lz4ChecksumMask)
+
+	lw.buf = lw.buf[:0]
+	_, err := lw.w.Write(lw.out)
+	return err
+}
+
+func lz4PutHeader(b []byte, method byte, compLen, origLen int, checksum uint32) {
+	copy(b, lz4Magic)
+	b[8] = method | (lz4BlockSizeLog - lz4LevelBase)
+	binary.LittleEndian.PutUint32(b[9:], uint32(compLen))
+	binary.LittleEndian.PutUint32(b[13:], uint32(origLen))
+	binary.LittleEndian.PutUint32(b[17:], checksum)
+}
+
+// Close writes the remaining data and the end of the stream.
+func (lw *lz4Writer) Close() error {
+	if lw.closed {
+		return nil
+	}
+	if err := lw.writeBlock(); err != nil {
+		return err
+	}
+	lw.closed = true
+
+	var end [lz4HeaderLen]byte
+	lz4PutHeader(end[:], lz4MethodRaw, 0, 0, 0)
+	_, err := lw.w.Write(end[:])
+	return err
+}
+
+type lz4Reader struct {
+	r    io.Reader
+	head [lz4HeaderLen]byte
+	comp []byte
+	buf  []byte // Uncompressed data of the current block.
+	pos  int    // Read position in buf.
+	done bool
+}
+
+func (lr *lz4Reader) Read(p []byte) (int, error) {
+	for lr.pos == len(lr.buf) {
+		if lr.done {
+			return 0, io.EOF
+		}
+		if err := lr.readBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, lr.buf[lr.pos:])
+	lr.pos += n
+	return n, nil
+}
+
+func (lr *lz4Reader) readBlock() error {
+	if _, err := io.ReadFull(lr.r, lr.head[:]); err != nil {
+		if err == io.EOF {
+			lr.done = true
+		}
+		return err
+	}
+	if string(lr.head[:len(lz4Magic)]) != lz4Magic {
+		return errors.New("nbt: invalid LZ4 block header")
+	}
+
+	method := lr.head[8] & 0xf0
+	maxLen := 1 << (lz4LevelBase + lr.head[8]&0x0f)
+	compLen := int(int32(binary.LittleEndian.Uint32(lr.head[9:])))
+	origLen := int(int32(binary.LittleEndian.Uint32(lr.head[13:])))
+	checksum := binary.LittleEndian.Uint32(lr.head[17:])
+	switch {
+	case method != lz4MethodRaw && method != lz4MethodLZ4,
+		origLen < 0 || origLen > maxLen,
+		compLen < 0 || compLen > lz4MaxCompressedLen(origLen),
+		(origLen == 0) != (compLen == 0),
+		method == lz4MethodRaw && compLen != origLen:
+		return errLZ4Corrupt
+	case origLen == 0:
+		if checksum != 0 {
+			return errLZ4Corrupt
+		}
+		lr.done = true
+		lr.buf, lr.pos = lr.buf[:0], 0
+		return nil
+	}
+
+	if cap(lr.comp) < compLen {
+		lr.comp = make([]byte, compLen)
+	}
+	lr.comp = lr.comp[:compLen]
+	if _, err := io.ReadFull(lr.r, lr.comp); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if cap(lr.buf) < origLen {
+		lr.buf = make([]byte, origLen)
+	}
+	lr.buf, lr.pos = lr.buf[:origLen], 0
+	if method == lz4MethodRaw {
+		copy(lr.buf, lr.comp)
+	} else if n, err := lz4DecompressBlock(lr.buf, lr.comp); err != nil {
+		return err
+	} else if n != origLen {
+		return errLZ4Corrupt
+	}
+
+	if xxh32(lr.buf, lz4Seed)&lz4ChecksumMask != checksum&lz4ChecksumMask {
+		return errors.New("nbt: LZ4 checksum mismatch")
+	}
+	return nil
+}
+
+// lz4MaxCompressedLen returns the maximum length of n bytes compressed in the LZ4 block format.
+func lz4MaxCompressedLen(n int) int {
+	return n + n/255 + 16
+}
+
+func lz4Hash(seq uint32) uint32 {
+	return (seq * 2654435761) >> (32 - lz4HashLog)
+}
+
+// lz4CompressBlock appends src compressed in the LZ4 block format to dst.
+func lz4CompressBlock(dst, src []byte) []byte {
+	var table [1 << lz4HashLog]int32 // Position+1 of the last sequence with a hash.
+
+	anchor := 0 // Start of the pending literals.
+	for i := 0; i+lz4MFLimit <= len(src); {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := lz4Hash(seq)
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i += 1 + (i-anchor)>>6 // Skip faster through incompressible data.
+			continue
+		}
+
+		for i > anchor && ref > 0 && src[i-1] == src[ref-1] {
+			i, ref = i-1, ref-1
+		}
+		end := i + lz4MinMatch
+		for end < len(src)-lz4LastLiterals && src[end] == src[ref+end-i] {
+			end++
+		}
+
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, end-i)
+		if end-2 > i {
+			table[lz4Hash(binary.LittleEndian.Uint32(src[end-2:]))] = int32(end - 2 + 1)
+		}
+		i, anchor = end, end
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+func lz4AppendLength(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+// lz4AppendSequence appends a sequence of literals and a match. The last sequence of a block has no match (matchLen 0).
+func lz4AppendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	token := byte(0)
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	if matchLen > 0 {
+		if ml := matchLen - lz4MinMatch; ml >= 15 {
+			token |= 15
+		} else {
+			token |= byte(ml)
+		}
+	}
+
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = lz4AppendLength(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	if matchLen == 0 {
+		return dst
+	}
+
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if ml := matchLen - lz4MinMatch; ml >= 15 {
+		dst = lz4AppendLength(dst, ml-15)
+	}
+	return dst
+}
+
+// lz4DecompressBlock decompresses src (in the LZ4 block format) into dst and returns the length of the data.
+func lz4DecompressBlock(dst, src []byte) (int, error) {
+	readLength := func(si, n int) (int, int, error) {
+		for {
+			if si >= len(src) {
+				return si, n, errLZ4Corrupt
+			}
+			b := src[si]
+			si++
+			n += int(b)
+			if b != 255 {
+				return si, n, nil
+			}
+		}
+	}
+
+	si, di := 0, 0
+	for {
+		if si >= len(src) {
+			return di, errLZ4Corrupt
+		}
+		token := src[si]
+		si++
+
+		var err error
+		lit := int(token >> 4)
+		if lit == 15 {
+			if si, lit, err = readLength(si, lit); err != nil {
+				return di, err
+			}
+		}
+		if lit > len(src)-si || lit > len(dst)-di {
+			return di, errLZ4Corrupt
+		}
+		di += copy(dst[di:], src[si:si+lit])
+		si += lit
+		if si == len(src) {
+			return di, nil // The last sequence has no match.
+		}
+
+		if si+2 > len(src) {
+			return di, errLZ4Corrupt
+		}
+		offset := int(src[si]) | int(src[si+1])<<8
+		si += 2
+		if offset == 0 || offset > di {
+			return di, errLZ4Corrupt
+		}
+
+		ml := int(token & 15)
+		if ml == 15 {
+			if si, ml, err = readLength(si, ml); err != nil {
+				return di, err
+			}
+		}
+		ml += lz4MinMatch
+		if ml > len(dst)-di {
+			return di, errLZ4Corrupt
+		}
+
+		if offset >= ml {
+			di += copy(dst[di:di+ml], dst[di-offset:])
+		} else {
+			// Overlapping match, repeating the last offset bytes.
+			for k := 0; k < ml; k++ {
+				dst[di+k] = dst[di-offset+k]
+			}
+			di += ml
+		}
+	}
+}
+
+const (
+	xxhPrime1 uint32 = 2654435761
+	xxhPrime2 uint32 = 2246822519
+	xxhPrime3 uint32 = 3266489917
+	xxhPrime4 uint32 = 668265263
+	xxhPrime5 uint32 = 374761393
+)
+
+func xxhRound(acc, input uint32) uint32 {
+	return bits.RotateLeft32(acc+input*xxhPrime2, 13) * xxhPrime1
+}
+
+// xxh32 computes the 32 bit xxHash of b.
+func xxh32(b []byte, seed uint32) uint32 {
+	n := len(b)
+	var h uint32
+	if n >= 16 {
+		v1 := seed + xxhPrime1 + xxhPrime2
+		v2 := seed + xxhPrime2
+		v3 := seed
+		v4 := seed - xxhPrime1
+		for ; len(b) >= 16; b = b[16:] {
+			v1 = xxhRound(v1, binary.LittleEndian.Uint32(b[0:]))
+			v2 = xxhRound(v2, binary.LittleEndian.Uint32(b[4:]))
+			v3 = xxhRound(v3, binary.LittleEndian.Uint32(b[8:]))
+			v4 = xxhRound(v4, binary.LittleEndian.Uint32(b[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxhPrime5
+	}
+
+	h += uint32(n)
+	for ; len(b) >= 4; b = b[4:] {
+		h += binary.LittleEndian.Uint32(b) * xxhPrime3
+		h = bits.RotateLeft32(h, 17) * xxhPrime4
+	}
+	for _, c := range b {
+		h += uint32(c) * xxhPrime5
+		h = bits.RotateLeft32(h, 11) * xxhPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxhPrime2
+	h ^= h >> 13
+	h *= xxhPrime3
+	h ^= h >> 16
+	return h
+}
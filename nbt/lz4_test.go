@@ -0,0 +1,157 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestXXH32(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0x02cc5d05},
+		{"a", 0, 0x550d7456},
+		{"abc", 0, 0x32d153ff},
+		{"Nobody inspects the spammish repetition", 0, 0xe2293b2f},
+	}
+	for _, test := range tests {
+		if have := xxh32([]byte(test.data), test.seed); have != test.want {
+			t.Errorf("xxh32(%q, %d): want %08x, have %08x", test.data, test.seed, test.want, have)
+		}
+	}
+}
+
+func lz4TestData() map[string][]byte {
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+	repeated := bytes.Repeat([]byte("abcdefgh"), 20000)
+	mixed := append(append([]byte{}, random[:5000]...), repeated[:70000]...)
+	return map[string][]byte{
+		"empty":    {},
+		"short":    []byte("hello"),
+		"run":      bytes.Repeat([]byte{0}, 1000),
+		"random":   random,
+		"repeated": repeated,
+		"mixed":    mixed,
+		"bigtest":  bigtest(),
+	}
+}
+
+func TestLZ4Block(t *testing.T) {
+	for name, data := range lz4TestData() {
+		comp := lz4CompressBlock(nil, data)
+		if len(comp) > lz4MaxCompressedLen(len(data)) {
+			t.Errorf("%s: Compressed length %d exceeds maximum", name, len(comp))
+		}
+		out := make([]byte, len(data))
+		n, err := lz4DecompressBlock(out, comp)
+		if err != nil || n != len(data) || !bytes.Equal(out, data) {
+			t.Errorf("%s: Round trip failed (n = %d, err = %v)", name, n, err)
+		}
+	}
+
+	if n := len(lz4CompressBlock(nil, bytes.Repeat([]byte("abcdefgh"), 20000))); n > 1000 {
+		t.Errorf("Repeated data compressed to %d bytes", n)
+	}
+
+	// Offset 5 points before the start of the output.
+	if _, err := lz4DecompressBlock(make([]byte, 100), []byte{0x10, 'a', 5, 0, 0x00}); err == nil {
+		t.Errorf("Invalid offset should fail")
+	}
+}
+
+func TestLZ4Stream(t *testing.T) {
+	for name, data := range lz4TestData() {
+		buf := new(bytes.Buffer)
+		w, _ := LZ4.NewWriter(buf)
+		// Write in odd pieces to cross block boundaries.
+		for p := data; len(p) > 0; {
+			n := 7777
+			if n > len(p) {
+				n = len(p)
+			}
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Fatalf("%s: Write failed: %s", name, err)
+			}
+			p = p[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close failed: %s", name, err)
+		}
+
+		stream := buf.Bytes()
+		if !bytes.HasPrefix(stream, []byte(lz4Magic)) || stream[8]&0x0f != lz4BlockSizeLog-lz4LevelBase {
+			t.Errorf("%s: Wrong block header % x", name, stream[:lz4HeaderLen])
+		}
+		if !bytes.HasSuffix(stream, append([]byte(lz4Magic), lz4MethodRaw|6, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)) {
+			t.Errorf("%s: Missing end block", name)
+		}
+
+		r, _ := LZ4.NewReader(bytes.NewReader(stream))
+		have, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(have, data) {
+			t.Errorf("%s: Round trip failed: %v", name, err)
+		}
+
+		if len(stream) > 2*lz4HeaderLen {
+			corrupt := append([]byte{}, stream...)
+			corrupt[lz4HeaderLen] ^= 0xff
+			r, _ := LZ4.NewReader(bytes.NewReader(corrupt))
+			if _, err := io.ReadAll(r); err == nil {
+				t.Errorf("%s: Corrupt data should fail", name)
+			}
+
+			r, _ = LZ4.NewReader(bytes.NewReader(stream[:len(stream)/2]))
+			if _, err := io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("%s: Truncated data: want io.ErrUnexpectedEOF, have %v", name, err)
+			}
+		}
+	}
+}
+
+// lz4JavaTestStream is a small chunk-like tag compressed in the format of lz4-java's LZ4BlockOutputStream, as used by
+// Minecraft. The block was compressed by the reference LZ4 implementation (lz4 1.9.4, which lz4-java's default
+// compressor binds), the header and XXH32 checksum were computed independently of this package.
+const lz4JavaTestStream = "4c5a34426c6f636b265d000000bc000000fe29450d" + // Header: LZ4, 93 of 188 bytes, checksum.
+	"f6210a0000080006537461747573000e6d696e6563726166743a66756c6c0900054974656d730a000000040800026964000f2200ff0073" +
+	"746f6e65010005436f756e74400020004df00403000b4461746156657273696f6e00000d8900" +
+	"4c5a34426c6f636b16000000000000000000000000" // End block.
+
+func TestLZ4KnownAnswer(t *testing.T) {
+	stream, _ := hex.DecodeString(lz4JavaTestStream)
+	want := mustSNBT(t, `{Status: "minecraft:full", DataVersion: 3465,
+		Items: [{id: "minecraft:stone", Count: 64b}, {id: "minecraft:stone", Count: 64b},
+			{id: "minecraft:stone", Count: 64b}, {id: "minecraft:stone", Count: 64b}]}`)
+
+	tag, _, c, err := ReadAnyNamedTag(bytes.NewReader(stream))
+	if err != nil || c != LZ4 || !Equal(tag, want) {
+		t.Fatalf("Wrong result %s (compression %v, err %v)", FormatSNBT(tag, SNBTOptions{}), c, err)
+	}
+
+	// The writer produces the same bytes as lz4-java.
+	r, _ := LZ4.NewReader(bytes.NewReader(stream))
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		string(raw): lz4JavaTestStream,
+		// Incompressible data is stored raw.
+		"hello": "4c5a34426c6f636b160500000005000000e3bf410a68656c6c6f4c5a34426c6f636b16000000000000000000000000",
+	}
+	for data, want := range tests {
+		buf := new(bytes.Buffer)
+		w, _ := LZ4.NewWriter(buf)
+		w.Write([]byte(data))
+		w.Close()
+		if have := hex.EncodeToString(buf.Bytes()); have != want {
+			t.Errorf("Writer output differs from lz4-java:\nwant %s\nhave %s", want, have)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package nbt
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Marshal converts v into a Tag tree.
+//
+// Go values are mapped to tags like this:
+//
+//	bool, int8, uint8          -- TAG_Byte
+//	int16, uint16              -- TAG_Short
+//	int32, uint32              -- TAG_Int
+//	int64, uint64, int, uint   -- TAG_Long
+//	float32                    -- TAG_Float
+//	float64                    -- TAG_Double
+//	string                     -- TAG_String
+//	[]byte, [N]byte            -- TAG_Byte_Array
+//	[]int32, [N]int32          -- TAG_Int_Array
+//	[]int64, [N]int64          -- TAG_Long_Array
+//	other slices and arrays    -- TAG_List
+//	structs, map[string]T      -- TAG_Compound
+//
+// Unsigned integers are stored with the same bits as their signed counterpart, so a uint16 of 65535 becomes a TAG_Short of -1.
+// Pointers and interfaces are marshalled as the value they point to, Tag, TagList and TagCompound values are
+// used as they are.
+//
+// Struct fields can be customized with a field tag under the "nbt" key. The first part of the tag is the name used in the compound,
+// an empty name keeps the field name. It can be followed by these comma separated options:
+//
+//	omitempty -- Leave the field out, if it has an empty value (false, 0, "", a nil pointer or interface, an empty slice, array or map).
+//	list      -- Store []byte, []int32 and []int64 as a TAG_List instead of an array tag.
+//	array     -- Store a slice or array of integers as TAG_Byte_Array, TAG_Int_Array or TAG_Long_Array, depending on the size of the integers.
+//	             16 bit integers and other element types result in an *UnsupportedTypeError.
+//
+// A field with the tag "-" is ignored, so are unexported fields. The fields of an embedded struct without a name in its tag are stored
+// as if they belonged to the outer struct.
+//
+// Nil pointers and interfaces in structs and maps are left out. Everywhere else they result in an error. Cyclic data
+// structures result in an *UnsupportedValueError, like in encoding/json.
+func Marshal(v interface{}) (Tag, error) {
+	s := &marshalState{ptrSeen: make(map[interface{}]struct{})}
+	tag, ok, err := s.marshalValue("", reflect.ValueOf(v), fieldOpts{})
+	if err != nil {
+		return Tag{}, err
+	}
+	if !ok {
+		return Tag{}, &UnsupportedValueError{Path: "", Msg: "nil value"}
+	}
+	return tag, nil
+}
+
+// UnsupportedTypeError is returned by Marshal and Unmarshal, if a Go type can not be represented in NBT.
+type UnsupportedTypeError struct {
+	Path string // Path of the value in the tag tree, e.g. "Inventory[2].id"
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("nbt: unsupported type %s at %s", e.Type, pathOrRoot(e.Path))
+}
+
+// UnsupportedValueError is returned by Marshal, if a value can not be represented in NBT.
+type UnsupportedValueError struct {
+	Path string
+	Msg  string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return fmt.Sprintf("nbt: unsupported value at %s: %s", pathOrRoot(e.Path), e.Msg)
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+type fieldOpts struct {
+	omitempty bool
+	list      bool
+	array     bool
+}
+
+type field struct {
+	name  string
+	index []int
+	opts  fieldOpts
+}
+
+func parseFieldTag(tag string) (string, fieldOpts) {
+	var opts fieldOpts
+	parts := strings.Split(tag, ",")
+	for _, o := range parts[1:] {
+		switch o {
+		case "omitempty":
+			opts.omitempty = true
+		case "list":
+			opts.list = true
+		case "array":
+			opts.array = true
+		}
+	}
+	return parts[0], opts
+}
+
+// structFields returns the fields of struct type t that are marshalled.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("nbt")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseFieldTag(tag)
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			for _, f := range structFields(sf.Type) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{name, []int{i}, opts})
+	}
+	return fields
+}
+
+var (
+	tagType     = reflect.TypeOf(Tag{})
+	tagListType = reflect.TypeOf(TagList{})
+)
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// arrayTagType returns the array TagType for slices / arrays with element type et.
+// If force is false, only the natural element types ([]byte, []int32, []int64) are considered.
+func arrayTagType(et reflect.Type, force bool) (TagType, bool) {
+	if !force {
+		switch et.Kind() {
+		case reflect.Uint8:
+			return TAG_Byte_Array, true
+		case reflect.Int32:
+			return TAG_Int_Array, true
+		case reflect.Int64:
+			return TAG_Long_Array, true
+		}
+		return 0, false
+	}
+
+	switch et.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return TAG_Byte_Array, true
+	case reflect.Int32, reflect.Uint32:
+		return TAG_Int_Array, true
+	case reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64:
+		return TAG_Long_Array, true
+	}
+	return 0, false
+}
+
+// staticTagType returns the TagType a value of type t will be marshalled as. TAG_End is returned, if this depends on the value.
+func staticTagType(t reflect.Type, opts fieldOpts) TagType {
+	switch t {
+	case tagType:
+		return TAG_End
+	case tagListType:
+		return TAG_List
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return TAG_Byte
+	case reflect.Int16, reflect.Uint16:
+		return TAG_Short
+	case reflect.Int32, reflect.Uint32:
+		return TAG_Int
+	case reflect.Int, reflect.Uint, reflect.Int64, reflect.Uint64:
+		return TAG_Long
+	case reflect.Float32:
+		return TAG_Float
+	case reflect.Float64:
+		return TAG_Double
+	case reflect.String:
+		return TAG_String
+	case reflect.Slice, reflect.Array:
+		if !opts.list {
+			if tt, ok := arrayTagType(t.Elem(), opts.array); ok {
+				return tt
+			}
+		}
+		return TAG_List
+	case reflect.Map, reflect.Struct:
+		return TAG_Compound
+	case reflect.Ptr:
+		return staticTagType(t.Elem(), opts)
+	}
+	return TAG_End
+}
+
+// startDetectingCyclesAfter is the number of nested pointers, maps and slices after which Marshal starts to check for
+// cycles. Checking earlier would slow down the common case, as in encoding/json.
+const startDetectingCyclesAfter = 1000
+
+// marshalState detects cycles in the marshalled value.
+type marshalState struct {
+	ptrLevel uint
+	ptrSeen  map[interface{}]struct{}
+}
+
+// ptrKey identifies the pointer, map or slice v. The type is included, as a struct and its first field share their address.
+type ptrKey struct {
+	ptr uintptr
+	len int
+	t   reflect.Type
+}
+
+// enter is called before the pointer, map or slice v is marshalled. leave must be called afterwards, even on errors.
+func (s *marshalState) enter(path string, v reflect.Value) error {
+	s.ptrLevel++
+	if s.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+	key := ptrKey{v.Pointer(), 0, v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := s.ptrSeen[key]; ok {
+		return &UnsupportedValueError{path, "encountered a cycle via " + v.Type().String()}
+	}
+	s.ptrSeen[key] = struct{}{}
+	return nil
+}
+
+func (s *marshalState) leave(v reflect.Value) {
+	if s.ptrLevel > startDetectingCyclesAfter {
+		key := ptrKey{v.Pointer(), 0, v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		delete(s.ptrSeen, key)
+	}
+	s.ptrLevel--
+}
+
+// marshalValue converts v into a Tag. ok is false, if v is a nil pointer or interface or a zero Tag.
+func (s *marshalState) marshalValue(path string, v reflect.Value, opts fieldOpts) (tag Tag, ok bool, err error) {
+	if !v.IsValid() {
+		return Tag{}, false, nil
+	}
+
+	switch v.Type() {
+	case tagType:
+		// A zero Tag is left out like a nil pointer, as TAG_End can't be stored in a compound.
+		tag := v.Interface().(Tag)
+		return tag, tag.Type != TAG_End || tag.Payload != nil, nil
+	case tagListType:
+		return Tag{TAG_List, v.Interface()}, true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return Tag{}, false, nil
+		}
+		if v.Kind() == reflect.Ptr {
+			err := s.enter(path, v)
+			defer s.leave(v)
+			if err != nil {
+				return Tag{}, false, err
+			}
+		}
+		return s.marshalValue(path, v.Elem(), opts)
+	case reflect.Bool:
+		var b byte
+		if v.Bool() {
+			b = 1
+		}
+		return Tag{TAG_Byte, b}, true, nil
+	case reflect.Int8:
+		return Tag{TAG_Byte, byte(v.Int())}, true, nil
+	case reflect.Uint8:
+		return Tag{TAG_Byte, byte(v.Uint())}, true, nil
+	case reflect.Int16:
+		return Tag{TAG_Short, int16(v.Int())}, true, nil
+	case reflect.Uint16:
+		return Tag{TAG_Short, int16(v.Uint())}, true, nil
+	case reflect.Int32:
+		return Tag{TAG_Int, int32(v.Int())}, true, nil
+	case reflect.Uint32:
+		return Tag{TAG_Int, int32(v.Uint())}, true, nil
+	case reflect.Int, reflect.Int64:
+		return Tag{TAG_Long, v.Int()}, true, nil
+	case reflect.Uint, reflect.Uint64:
+		return Tag{TAG_Long, int64(v.Uint())}, true, nil
+	case reflect.Float32:
+		return Tag{TAG_Float, float32(v.Float())}, true, nil
+	case reflect.Float64:
+		return Tag{TAG_Double, v.Float()}, true, nil
+	case reflect.String:
+		return Tag{TAG_String, v.String()}, true, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			err := s.enter(path, v)
+			defer s.leave(v)
+			if err != nil {
+				return Tag{}, false, err
+			}
+		}
+		tag, err := s.marshalSlice(path, v, opts)
+		return tag, err == nil, err
+	case reflect.Map:
+		err := s.enter(path, v)
+		defer s.leave(v)
+		if err != nil {
+			return Tag{}, false, err
+		}
+		tag, err := s.marshalMap(path, v)
+		return tag, err == nil, err
+	case reflect.Struct:
+		tag, err := s.marshalStruct(path, v)
+		return tag, err == nil, err
+	}
+
+	return Tag{}, false, &UnsupportedTypeError{path, v.Type()}
+}
+
+func (s *marshalState) marshalSlice(path string, v reflect.Value, opts fieldOpts) (Tag, error) {
+	n := v.Len()
+	et := v.Type().Elem()
+
+	if !opts.list {
+		tt, ok := arrayTagType(et, opts.array)
+		if !ok && opts.array {
+			return Tag{}, &UnsupportedTypeError{path, v.Type()}
+		}
+		if ok {
+			switch tt {
+			case TAG_Byte_Array:
+				data := make([]byte, n)
+				for i := range data {
+					tag, _, _ := s.marshalValue("", v.Index(i), fieldOpts{})
+					data[i] = tag.Payload.(byte)
+				}
+				return Tag{tt, data}, nil
+			case TAG_Int_Array:
+				data := make([]int32, n)
+				for i := range data {
+					tag, _, _ := s.marshalValue("", v.Index(i), fieldOpts{})
+					data[i] = tag.Payload.(int32)
+				}
+				return Tag{tt, data}, nil
+			case TAG_Long_Array:
+				data := make([]int64, n)
+				for i := range data {
+					tag, _, _ := s.marshalValue("", v.Index(i), fieldOpts{})
+					data[i] = tag.Payload.(int64)
+				}
+				return Tag{tt, data}, nil
+			}
+		}
+	}
+
+	l := TagList{Type: staticTagType(et, fieldOpts{}), Elems: make([]interface{}, n)}
+	for i := 0; i < n; i++ {
+		ipath := indexPath(path, i)
+		tag, ok, err := s.marshalValue(ipath, v.Index(i), fieldOpts{})
+		if err != nil {
+			return Tag{}, err
+		}
+		if !ok {
+			return Tag{}, &UnsupportedValueError{ipath, "nil value in list"}
+		}
+
+		if i == 0 && l.Type == TAG_End {
+			l.Type = tag.Type
+		}
+		if tag.Type != l.Type {
+			return Tag{}, &UnsupportedValueError{ipath, fmt.Sprintf("%s in list of %s", tag.Type, l.Type)}
+		}
+		l.Elems[i] = tag.Payload
+	}
+	return Tag{TAG_List, l}, nil
+}
+
+func (s *marshalState) marshalMap(path string, v reflect.Value) (Tag, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return Tag{}, &UnsupportedTypeError{path, v.Type()}
+	}
+
+	comp := make(TagCompound)
+	iter := v.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		tag, ok, err := s.marshalValue(joinPath(path, key), iter.Value(), fieldOpts{})
+		if err != nil {
+			return Tag{}, err
+		}
+		if ok {
+			comp[key] = tag
+		}
+	}
+	return Tag{TAG_Compound, comp}, nil
+}
+
+func (s *marshalState) marshalStruct(path string, v reflect.Value) (Tag, error) {
+	comp := make(TagCompound)
+	for _, f := range structFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if f.opts.omitempty && isEmptyValue(fv) {
+			continue
+		}
+
+		tag, ok, err := s.marshalValue(joinPath(path, f.name), fv, f.opts)
+		if err != nil {
+			return Tag{}, err
+		}
+		if ok {
+			comp[f.name] = tag
+		}
+	}
+	return Tag{TAG_Compound, comp}, nil
+}
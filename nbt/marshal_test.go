@@ -0,0 +1,250 @@
+package nbt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type marshalTestItem struct {
+	Slot  int8   `nbt:"Slot"`
+	ID    string `nbt:"id"`
+	Count byte
+	Tag   *marshalTestDisplay `nbt:"tag,omitempty"`
+}
+
+type marshalTestDisplay struct {
+	Name string `nbt:"name"`
+}
+
+type marshalTestPos struct {
+	X, Y, Z int32
+}
+
+type marshalTestPlayer struct {
+	marshalTestPos
+	Name      string            `nbt:"name"`
+	Health    float32           `nbt:"Health"`
+	OnGround  bool              `nbt:"OnGround"`
+	XP        uint16            `nbt:"XpLevel"`
+	Seed      uint64            `nbt:"seed"`
+	Motion    []float64         `nbt:"Motion"`
+	Inventory []marshalTestItem `nbt:"Inventory"`
+	Flags     []byte            `nbt:"flags,list"`
+	Blocks    []int             `nbt:"blocks,array"`
+	UUID      [4]int32          `nbt:"UUID"`
+	Extra     map[string]int16  `nbt:"extra"`
+	Note      string            `nbt:"note,omitempty"`
+	Ignored   string            `nbt:"-"`
+	Raw       Tag               `nbt:"raw"`
+	secret    int
+}
+
+func TestMarshalRoundtrip(t *testing.T) {
+	in := marshalTestPlayer{
+		marshalTestPos: marshalTestPos{1, 64, -3},
+		Name:           "Steve",
+		Health:         19.5,
+		OnGround:       true,
+		XP:             65535,
+		Seed:           1 << 63,
+		Motion:         []float64{0.5, -0.25, 0},
+		Inventory: []marshalTestItem{
+			{Slot: 0, ID: "minecraft:stone", Count: 64},
+			{Slot: -106, ID: "minecraft:diamond_sword", Count: 1, Tag: &marshalTestDisplay{"Excalibur"}},
+		},
+		Flags:   []byte{1, 0, 1},
+		Blocks:  []int{1, 2, 3},
+		UUID:    [4]int32{1, 2, 3, 4},
+		Extra:   map[string]int16{"a": 1, "b": -2},
+		Ignored: "not stored",
+		Raw:     NewStringTag("raw"),
+		secret:  42,
+	}
+
+	tag, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	if tag.Type != TAG_Compound {
+		t.Fatalf("Marshal returned %s, expected TAG_Compound", tag.Type)
+	}
+	comp := tag.Payload.(TagCompound)
+
+	for _, key := range []string{"Ignored", "note", "secret", "marshalTestPos"} {
+		if _, ok := comp[key]; ok {
+			t.Errorf("Key %#v should not be stored", key)
+		}
+	}
+	if tag, ok := getKey(t, comp, "X", TAG_Int); ok {
+		tagPayloadCompare(t, "X", tag, int32(1))
+	}
+	if tag, ok := getKey(t, comp, "XpLevel", TAG_Short); ok {
+		tagPayloadCompare(t, "XpLevel", tag, int16(-1))
+	}
+	if tag, ok := getKey(t, comp, "OnGround", TAG_Byte); ok {
+		tagPayloadCompare(t, "OnGround", tag, byte(1))
+	}
+	getKey(t, comp, "Motion", TAG_List)
+	getKey(t, comp, "flags", TAG_List)
+	getKey(t, comp, "blocks", TAG_Long_Array)
+	getKey(t, comp, "UUID", TAG_Int_Array)
+	if tag, ok := getKey(t, comp, "Inventory", TAG_List); ok {
+		l := tag.Payload.(TagList)
+		if l.Type != TAG_Compound || len(l.Elems) != 2 {
+			t.Fatalf("Inventory is %s list of length %d, expected TAG_Compound list of length 2", l.Type, len(l.Elems))
+		}
+		if _, ok := l.Elems[0].(TagCompound)["tag"]; ok {
+			t.Errorf("Inventory[0].tag should be omitted")
+		}
+	}
+
+	// Also take a trip through the binary format.
+	buf := new(bytes.Buffer)
+	if err := WriteNamedTag(buf, "", tag); err != nil {
+		t.Fatalf("Could not write NBT data: %s", err)
+	}
+	if tag, _, err = ReadNamedTag(buf); err != nil {
+		t.Fatalf("Could not read NBT data: %s", err)
+	}
+
+	var out marshalTestPlayer
+	if err := Unmarshal(tag, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+
+	in.Ignored = ""
+	in.secret = 0
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Roundtrip changed value.\nWant: %#v\nHave: %#v", in, out)
+	}
+}
+
+func TestMarshalEmptyList(t *testing.T) {
+	tag, err := Marshal(struct{ L []string }{})
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	l, err := tag.Payload.(TagCompound).GetList("L")
+	if err != nil {
+		t.Fatalf("Could not get L: %s", err)
+	}
+	if l.Type != TAG_String || len(l.Elems) != 0 {
+		t.Errorf("L is %s list of length %d, expected empty TAG_String list", l.Type, len(l.Elems))
+	}
+}
+
+func TestUnmarshalEnd(t *testing.T) {
+	// An empty item slot, as returned by ReadNamelessTag.
+	var v interface{} = "old"
+	if err := Unmarshal(Tag{Type: TAG_End}, &v); err != nil || v != nil {
+		t.Errorf("Want nil, have %#v (err %v)", v, err)
+	}
+}
+
+func TestMarshalZeroTag(t *testing.T) {
+	tag, err := Marshal(struct {
+		Raw Tag `nbt:"raw"`
+		N   int32
+	}{N: 1})
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+	if _, ok := tag.Payload.(TagCompound)["raw"]; ok {
+		t.Errorf("Zero Tag should be left out")
+	}
+	if err := WriteNamedTag(new(bytes.Buffer), "", tag); err != nil {
+		t.Errorf("Could not write marshalled tag: %s", err)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := Marshal(map[string]interface{}{"list": []interface{}{int32(1), "two"}})
+	if e, ok := err.(*UnsupportedValueError); !ok || e.Path != "list[1]" {
+		t.Errorf("Mixed list: want *UnsupportedValueError at list[1], have %#v", err)
+	}
+
+	_, err = Marshal(struct{ C chan int }{make(chan int)})
+	if e, ok := err.(*UnsupportedTypeError); !ok || e.Path != "C" {
+		t.Errorf("Channel: want *UnsupportedTypeError at C, have %#v", err)
+	}
+
+	for _, v := range []interface{}{
+		struct {
+			S []int16 `nbt:",array"`
+		}{[]int16{1}},
+		struct {
+			F []float32 `nbt:",array"`
+		}{},
+		struct {
+			S *[]string `nbt:",array"`
+		}{&[]string{"a"}},
+	} {
+		_, err := Marshal(v)
+		if _, ok := err.(*UnsupportedTypeError); !ok {
+			t.Errorf("%T: want *UnsupportedTypeError, have %#v", v, err)
+		}
+	}
+}
+
+func TestMarshalCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "a"}
+	n.Next = &node{Name: "b", Next: n}
+
+	m := map[string]interface{}{}
+	m["self"] = m
+
+	s := []interface{}{nil}
+	s[0] = s
+
+	for _, v := range []interface{}{n, m, s} {
+		_, err := Marshal(v)
+		if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("%T: want *UnsupportedValueError, have %v", v, err)
+		}
+	}
+
+	// Deep data without cycles is fine, also where cycles are checked for.
+	var deep *node
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		deep = &node{Name: "x", Next: deep}
+	}
+	if _, err := Marshal([]*node{deep, deep}); err != nil {
+		t.Errorf("Deep data: unexpected error %v", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tag, err := Marshal(map[string]interface{}{
+		"Inventory": []map[string]interface{}{
+			{"Count": byte(1)},
+			{"Count": "many"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %s", err)
+	}
+
+	var v struct{ Inventory []marshalTestItem }
+	err = Unmarshal(tag, &v)
+	e, ok := err.(*UnmarshalTypeError)
+	if !ok {
+		t.Fatalf("Want *UnmarshalTypeError, have %#v", err)
+	}
+	if e.Path != "Inventory[1].Count" || e.Tag != TAG_String {
+		t.Errorf("Wrong error: %s", e)
+	}
+
+	var small struct{ X int8 }
+	if err := Unmarshal(Tag{TAG_Compound, TagCompound{"X": NewIntTag(300)}}, &small); err == nil {
+		t.Errorf("Unmarshalling 300 into an int8 should fail")
+	}
+
+	if err := Unmarshal(NewIntTag(1), small); err == nil {
+		t.Errorf("Unmarshal into non-pointer should fail")
+	}
+}
@@ -0,0 +1,406 @@
+package nbt
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ModifyError is returned by the path based modification methods (Tag.Set etc.), if a matched tag can not be changed.
+type ModifyError struct {
+	Path string // Concrete path of the tag.
+	Msg  string
+}
+
+func (e *ModifyError) Error() string {
+	return fmt.Sprintf("nbt: can not modify %s: %s", pathOrRoot(e.Path), e.Msg)
+}
+
+// The path based modification methods work like the /data modify command of Minecraft. They change the tree in place
+// and return the number of tags changed. If an error occurs, nothing is changed: the new tags are validated (see
+// Tag.Validate) first, and paths that can match several tags are tried on a copy of the tree before.
+
+// Set sets all tags matching p to a copy of v. Missing compound entries are created, including intermediate compounds,
+// but a missing list or array can't be created for an index. A list element can only be set to a tag of the lists type.
+// With an empty path, t itself is replaced.
+func (t *Tag) Set(p Path, v Tag) (int, error) {
+	if err := v.Validate(); err != nil {
+		return 0, err
+	}
+	create := func() Tag { return Tag{} }
+	return modify(t, p, create, func(target *Tag, path Path) (int, error) {
+		if Equal(*target, v) {
+			return 0, nil
+		}
+		*target = v.Clone()
+		return 1, nil
+	})
+}
+
+// Remove removes all tags matching p from their compounds, lists or arrays.
+func (t *Tag) Remove(p Path) (int, error) {
+	if len(p) == 0 {
+		return 0, &ModifyError{"", "can not remove the root tag"}
+	}
+	last := p[len(p)-1]
+	return modify(t, p[:len(p)-1], nil, func(parent *Tag, path Path) (int, error) {
+		return removeMatches(parent, last, path)
+	})
+}
+
+// Append appends a copy of v to all lists and arrays matching p. A missing list is created.
+func (t *Tag) Append(p Path, v Tag) (int, error) {
+	return t.Insert(p, -1, v)
+}
+
+// Insert inserts a copy of v at index i into all lists and arrays matching p. A negative index counts from the end,
+// -1 appends. A missing list is created. The type of v must match the type of the lists elements, except for empty lists
+// of TAG_End, which adopt the type of v.
+func (t *Tag) Insert(p Path, i int, v Tag) (int, error) {
+	if err := v.Validate(); err != nil {
+		return 0, err
+	}
+	create := func() Tag { return Tag{TAG_List, TagList{Type: TAG_End, Elems: []interface{}{}}} }
+	return modify(t, p, create, func(target *Tag, path Path) (int, error) {
+		n := elemCount(*target)
+		j := i
+		if j < 0 {
+			j += n + 1
+		}
+		if j < 0 || j > n {
+			return 0, &ModifyError{path.String(), fmt.Sprintf("index %d out of range", i)}
+		}
+		if err := insertElem(target, j, v.Clone(), path); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+}
+
+// Merge merges the compound v into all compounds matching p. Nested compounds are merged recursively, other entries of v
+// replace existing ones. Missing compounds are created.
+func (t *Tag) Merge(p Path, v Tag) (int, error) {
+	if v.Type != TAG_Compound {
+		return 0, &ModifyError{p.String(), "can only merge a compound"}
+	}
+	if err := v.Validate(); err != nil {
+		return 0, err
+	}
+	create := func() Tag { return Tag{TAG_Compound, TagCompound{}} }
+	return modify(t, p, create, func(target *Tag, path Path) (int, error) {
+		if target.Type != TAG_Compound {
+			return 0, &ModifyError{path.String(), "not a compound"}
+		}
+		if mergeInto(target, v) {
+			return 1, nil
+		}
+		return 0, nil
+	})
+}
+
+// Set is like Tag.Set. The path must not end at the compound itself.
+func (tc TagCompound) Set(p Path, v Tag) (int, error) {
+	if endsAtRoot(p) {
+		return 0, &ModifyError{p.String(), "can not replace a TagCompound"}
+	}
+	t := Tag{TAG_Compound, tc}
+	return t.Set(p, v)
+}
+
+// Remove is like Tag.Remove.
+func (tc TagCompound) Remove(p Path) (int, error) {
+	t := Tag{TAG_Compound, tc}
+	return t.Remove(p)
+}
+
+// Append is like Tag.Append.
+func (tc TagCompound) Append(p Path, v Tag) (int, error) {
+	t := Tag{TAG_Compound, tc}
+	return t.Append(p, v)
+}
+
+// Insert is like Tag.Insert.
+func (tc TagCompound) Insert(p Path, i int, v Tag) (int, error) {
+	t := Tag{TAG_Compound, tc}
+	return t.Insert(p, i, v)
+}
+
+// Merge is like Tag.Merge.
+func (tc TagCompound) Merge(p Path, v Tag) (int, error) {
+	t := Tag{TAG_Compound, tc}
+	return t.Merge(p, v)
+}
+
+func endsAtRoot(p Path) bool {
+	for _, elem := range p {
+		if elem.Kind != PathRoot {
+			return false
+		}
+	}
+	return true
+}
+
+// modify calls modifyPath for t. If p can match several tags, it is tried on a copy of t first, so t is not changed,
+// if an error occurs after some tags were changed already.
+func modify(t *Tag, p Path, create func() Tag, fn func(*Tag, Path) (int, error)) (int, error) {
+	for _, elem := range p {
+		if elem.Kind == PathAll || elem.Kind == PathFilter {
+			tc := t.Clone()
+			if _, err := modifyPath(&tc, p, Path{}, create, fn); err != nil {
+				return 0, err
+			}
+			break
+		}
+	}
+	return modifyPath(t, p, Path{}, create, fn)
+}
+
+// modifyPath calls fn for all tags matching p in t and writes changed tags back into their parents. path is the
+// concrete path of t. If create is not nil, missing compound entries are created: the last one with create, intermediate
+// ones as compounds.
+func modifyPath(t *Tag, p Path, path Path, create func() Tag, fn func(*Tag, Path) (int, error)) (int, error) {
+	if len(p) == 0 {
+		return fn(t, path)
+	}
+
+	elem, rest := p[0], p[1:]
+	switch elem.Kind {
+	case PathRoot:
+		if !matchTag(elem.Filter, *t) {
+			return 0, nil
+		}
+		return modifyPath(t, rest, path, create, fn)
+	case PathKey:
+		child, ok := compoundEntry(*t, elem.Name)
+		switch {
+		case !ok:
+			switch {
+			case create == nil:
+				return 0, nil
+			case t.Type != TAG_Compound:
+				return 0, &ModifyError{path.String(), "not a compound"}
+			case len(rest) == 0:
+				child = create()
+			case elem.Filter.Type == TAG_Compound:
+				child = elem.Filter.Clone()
+			case rest[0].Kind == PathKey:
+				child = Tag{TAG_Compound, TagCompound{}}
+			default:
+				return 0, &ModifyError{path.Key(elem.Name).String(), "does not exist and can not be created"}
+			}
+		case elem.Filter.Type == TAG_Compound && !matchTag(elem.Filter, child):
+			return 0, nil
+		}
+
+		n, err := modifyPath(&child, rest, path.Key(elem.Name), create, fn)
+		if n > 0 {
+			setEntry(t, elem.Name, child)
+		}
+		return n, err
+	case PathIndex:
+		i := elem.Index
+		if i < 0 {
+			i += elemCount(*t)
+		}
+		if i < 0 || i >= elemCount(*t) {
+			return 0, nil
+		}
+		return modifyElem(t, i, rest, path, create, fn)
+	case PathAll, PathFilter:
+		count := 0
+		for i, n := 0, elemCount(*t); i < n; i++ {
+			if elem.Kind == PathFilter && !matchTag(elem.Filter, elemAt(*t, i)) {
+				continue
+			}
+			c, err := modifyElem(t, i, rest, path, create, fn)
+			count += c
+			if err != nil {
+				return count, err
+			}
+		}
+		return count, nil
+	}
+	return 0, nil
+}
+
+func modifyElem(t *Tag, i int, rest Path, path Path, create func() Tag, fn func(*Tag, Path) (int, error)) (int, error) {
+	child := elemAt(*t, i)
+	n, err := modifyPath(&child, rest, path.Index(i), create, fn)
+	if n > 0 {
+		if err := setElem(t, i, child, path.Index(i)); err != nil {
+			return 0, err
+		}
+	}
+	return n, err
+}
+
+// removeMatches removes the children of parent matching elem.
+func removeMatches(parent *Tag, elem PathElem, path Path) (int, error) {
+	switch elem.Kind {
+	case PathRoot:
+		return 0, &ModifyError{path.String(), "can not remove the root tag"}
+	case PathKey:
+		child, ok := compoundEntry(*parent, elem.Name)
+		if !ok || elem.Filter.Type == TAG_Compound && !matchTag(elem.Filter, child) {
+			return 0, nil
+		}
+		return removeEntry(parent, elem.Name), nil
+	case PathIndex:
+		idx := elem.Index
+		if idx < 0 {
+			idx += elemCount(*parent)
+		}
+		return removeElems(parent, func(i int, _ Tag) bool { return i == idx }), nil
+	case PathAll:
+		return removeElems(parent, func(int, Tag) bool { return true }), nil
+	case PathFilter:
+		return removeElems(parent, func(_ int, e Tag) bool { return matchTag(elem.Filter, e) }), nil
+	}
+	return 0, nil
+}
+
+func setEntry(t *Tag, name string, v Tag) {
+	switch comp := t.Payload.(type) {
+	case TagCompound:
+		if comp == nil {
+			comp = make(TagCompound)
+			t.Payload = comp
+		}
+		comp[name] = v
+	}
+}
+
+// removeEntry removes the entry name and returns the number of entries removed.
+func removeEntry(t *Tag, name string) int {
+	switch comp := t.Payload.(type) {
+	case TagCompound:
+		if _, ok := comp[name]; ok {
+			delete(comp, name)
+			return 1
+		}
+	}
+	return 0
+}
+
+// arrayElemType returns the type of the elements of an array type, or TAG_End for other types.
+func arrayElemType(tt TagType) TagType {
+	switch tt {
+	case TAG_Byte_Array:
+		return TAG_Byte
+	case TAG_Int_Array:
+		return TAG_Int
+	case TAG_Long_Array:
+		return TAG_Long
+	}
+	return TAG_End
+}
+
+// checkElem checks, if the valid tag v can be an element of the list or array t.
+func checkElem(t Tag, v Tag, path Path) error {
+	l, isList := t.Payload.(TagList)
+	et := arrayElemType(t.Type)
+	switch {
+	case isList && t.Type == TAG_List:
+		et = l.Type
+	case et == TAG_End:
+		return &ModifyError{path.String(), "not a list or array"}
+	}
+
+	if v.Type != et || v.Type == TAG_End {
+		return &ModifyError{path.String(), fmt.Sprintf("%s does not match element type %s", v.Type, et)}
+	}
+	if s := reflect.TypeOf(t.Payload); !isList && (s == nil || s.Kind() != reflect.Slice || s.Elem() != reflect.TypeOf(v.Payload)) {
+		return payloadMismatch(path.String(), t.Type, t.Payload)
+	}
+	return nil
+}
+
+func setElem(t *Tag, i int, v Tag, path Path) error {
+	if err := checkElem(*t, v, path); err != nil {
+		return err
+	}
+	if l, ok := t.Payload.(TagList); ok {
+		l.Elems[i] = v.Payload
+		return nil
+	}
+	reflect.ValueOf(t.Payload).Index(i).Set(reflect.ValueOf(v.Payload))
+	return nil
+}
+
+func insertElem(t *Tag, i int, v Tag, path Path) error {
+	if l, ok := t.Payload.(TagList); ok && l.Type == TAG_End && len(l.Elems) == 0 {
+		t.Payload = TagList{Type: v.Type, Elems: l.Elems}
+	}
+	if err := checkElem(*t, v, path); err != nil {
+		return err
+	}
+
+	if l, ok := t.Payload.(TagList); ok {
+		l.Elems = append(l.Elems, nil)
+		copy(l.Elems[i+1:], l.Elems[i:])
+		l.Elems[i] = v.Payload
+		t.Payload = l
+		return nil
+	}
+
+	s := reflect.ValueOf(t.Payload)
+	s = reflect.Append(s, reflect.Zero(s.Type().Elem()))
+	reflect.Copy(s.Slice(i+1, s.Len()), s.Slice(i, s.Len()-1))
+	s.Index(i).Set(reflect.ValueOf(v.Payload))
+	t.Payload = s.Interface()
+	return nil
+}
+
+// removeElems removes all elements of a list or array for which del returns true, and returns the number of elements removed.
+func removeElems(t *Tag, del func(int, Tag) bool) int {
+	n := elemCount(*t)
+	if l, ok := t.Payload.(TagList); ok {
+		elems := make([]interface{}, 0, n)
+		for i, e := range l.Elems {
+			if !del(i, Tag{l.Type, e}) {
+				elems = append(elems, e)
+			}
+		}
+		t.Payload = TagList{l.Type, elems}
+		return n - len(elems)
+	}
+
+	if arrayElemType(t.Type) == TAG_End || n == 0 {
+		return 0
+	}
+	s := reflect.ValueOf(t.Payload)
+	out := reflect.MakeSlice(s.Type(), 0, n)
+	for i := 0; i < n; i++ {
+		if !del(i, elemAt(*t, i)) {
+			out = reflect.Append(out, s.Index(i))
+		}
+	}
+	t.Payload = out.Interface()
+	return n - out.Len()
+}
+
+// mergeInto merges the compound src into the compound dst and reports, if dst was changed.
+func mergeInto(dst *Tag, src Tag) bool {
+	changed := false
+	merge := func(name string, tag Tag) {
+		have, ok := compoundEntry(*dst, name)
+		switch {
+		case ok && have.Type == TAG_Compound && tag.Type == TAG_Compound:
+			if mergeInto(&have, tag) {
+				setEntry(dst, name, have)
+				changed = true
+			}
+		case ok && Equal(have, tag):
+		default:
+			setEntry(dst, name, tag.Clone())
+			changed = true
+		}
+	}
+
+	switch comp := src.Payload.(type) {
+	case TagCompound:
+		for name, tag := range comp {
+			merge(name, tag)
+		}
+	}
+	return changed
+}
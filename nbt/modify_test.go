@@ -0,0 +1,197 @@
+package nbt
+
+import (
+	"errors"
+	"testing"
+)
+
+func mustSNBT(t *testing.T, s string) Tag {
+	tag, err := ParseSNBT(s)
+	if err != nil {
+		t.Fatalf("Could not parse %s: %s", s, err)
+	}
+	return tag
+}
+
+func TestModify(t *testing.T) {
+	tests := []struct {
+		op   func(tag *Tag) (int, error)
+		n    int
+		want string
+	}{
+		{
+			func(tag *Tag) (int, error) { return tag.Set(MustParsePath("a.b.c"), NewIntTag(1)) },
+			1, `{a:{b:{c:1}},list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Set(MustParsePath("items[].n"), NewByteTag(2)) },
+			1, `{list:[1s,2s,3s],items:[{id:"x",n:2b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Set(MustParsePath("items[{id:\"y\"}].n"), NewByteTag(5)) },
+			1, `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:5b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Set(MustParsePath("list[-1]"), NewShortTag(9)) },
+			1, `{list:[1s,2s,9s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Set(MustParsePath("arr[0]"), NewIntTag(7)) },
+			1, `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;7,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Remove(MustParsePath("items[{id:\"x\"}]")) },
+			1, `{list:[1s,2s,3s],items:[{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Remove(MustParsePath("items[].n")) },
+			2, `{list:[1s,2s,3s],items:[{id:"x"},{id:"y"}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Remove(MustParsePath("list[]")) },
+			3, `{list:[],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Remove(MustParsePath("arr[-2]")) },
+			1, `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Append(MustParsePath("list"), NewShortTag(4)) },
+			1, `{list:[1s,2s,3s,4s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Insert(MustParsePath("arr"), 1, NewIntTag(5)) },
+			1, `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,5,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Insert(MustParsePath("list"), -2, NewShortTag(0)) },
+			1, `{list:[1s,2s,0s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) { return tag.Append(MustParsePath("new.list"), NewStringTag("s")) },
+			1, `{new:{list:["s"]},list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) {
+				return tag.Merge(MustParsePath("items[]"), Tag{TAG_Compound, TagCompound{"n": NewByteTag(1), "tag": NewCompoundTag()}})
+			},
+			2, `{list:[1s,2s,3s],items:[{id:"x",n:1b,tag:{}},{id:"y",n:1b,tag:{}}],arr:[I;1,2]}`,
+		},
+		{
+			func(tag *Tag) (int, error) {
+				src := Tag{TAG_Compound, TagCompound{"items": NewStringTag("gone"), "x": Tag{TAG_Compound, TagCompound{"y": NewIntTag(1)}}}}
+				return tag.Merge(Path{}, src)
+			},
+			1, `{list:[1s,2s,3s],items:"gone",arr:[I;1,2],x:{y:1}}`,
+		},
+		{
+			func(tag *Tag) (int, error) {
+				return tag.Merge(MustParsePath("items[0]"), Tag{TAG_Compound, TagCompound{"id": NewStringTag("x")}})
+			},
+			0, `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`,
+		},
+	}
+
+	const start = `{list:[1s,2s,3s],items:[{id:"x",n:1b},{id:"y",n:2b}],arr:[I;1,2]}`
+	for i, test := range tests {
+		tag := mustSNBT(t, start)
+		n, err := test.op(&tag)
+		if err != nil {
+			t.Errorf("Test %d: Unexpected error: %s", i, err)
+			continue
+		}
+		if n != test.n {
+			t.Errorf("Test %d: Want %d changes, have %d", i, test.n, n)
+		}
+		if have, want := FormatSNBT(tag, SNBTOptions{}), FormatSNBT(mustSNBT(t, test.want), SNBTOptions{}); have != want {
+			t.Errorf("Test %d: Wrong result.\nWant: %s\nHave: %s", i, want, have)
+		}
+	}
+}
+
+func TestModifyErrors(t *testing.T) {
+	tag := mustSNBT(t, `{list:[1s,2s],arr:[B;1b],s:"str"}`)
+
+	var me *ModifyError
+	if _, err := tag.Append(MustParsePath("list"), NewIntTag(3)); !errors.As(err, &me) || me.Path != "list" {
+		t.Errorf("Appending an int to a list of shorts: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Set(MustParsePath("list[0]"), NewStringTag("x")); !errors.As(err, &me) {
+		t.Errorf("Setting a list element to a wrong type: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Append(MustParsePath("arr"), NewIntTag(3)); !errors.As(err, &me) {
+		t.Errorf("Appending an int to a byte array: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Append(MustParsePath("s"), NewIntTag(3)); !errors.As(err, &me) {
+		t.Errorf("Appending to a string: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Insert(MustParsePath("list"), 5, NewShortTag(3)); !errors.As(err, &me) {
+		t.Errorf("Inserting out of range: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Merge(MustParsePath("s"), NewCompoundTag()); !errors.As(err, &me) {
+		t.Errorf("Merging into a string: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Set(MustParsePath("x"), Tag{TAG_Int, "no int"}); err == nil {
+		t.Errorf("Setting an invalid tag should fail")
+	}
+	if _, err := tag.Set(MustParsePath("missing[0]"), NewIntTag(7)); !errors.As(err, &me) || me.Path != "missing" {
+		t.Errorf("Setting an element of a missing list: want ModifyError, have %v", err)
+	}
+	if _, err := tag.Set(MustParsePath("s.x"), NewIntTag(7)); !errors.As(err, &me) || me.Path != "s" {
+		t.Errorf("Setting an entry of a string: want ModifyError, have %v", err)
+	}
+
+	if want := `{arr:[B;1b],list:[1s,2s],s:"str"}`; FormatSNBT(tag, SNBTOptions{}) != want {
+		t.Errorf("Failed operations changed the tag to %s", FormatSNBT(tag, SNBTOptions{}))
+	}
+
+	comp := tag.Payload.(TagCompound)
+	if _, err := comp.Set(Path{}, NewIntTag(1)); err == nil {
+		t.Errorf("Replacing a TagCompound should fail")
+	}
+	if n, err := comp.Set(MustParsePath("s"), NewStringTag("new")); n != 1 || err != nil {
+		t.Errorf("TagCompound.Set: want 1 change, have %d (err: %v)", n, err)
+	}
+	if s, _ := comp.GetString("s"); s != "new" {
+		t.Errorf("TagCompound.Set did not change the compound")
+	}
+}
+
+func TestModifyAtomic(t *testing.T) {
+	tests := []struct {
+		start string
+		op    func(tag *Tag) (int, error)
+	}{
+		{`{l:[{a:[1s]},{a:[B;1b]}]}`, func(tag *Tag) (int, error) { return tag.Append(MustParsePath("l[].a"), NewShortTag(2)) }},
+		{`{l:[{a:{}},{a:1}]}`, func(tag *Tag) (int, error) {
+			return tag.Merge(MustParsePath("l[].a"), Tag{TAG_Compound, TagCompound{"x": NewIntTag(1)}})
+		}},
+		{`{l:[{a:{b:[1]}},{a:{}}]}`, func(tag *Tag) (int, error) { return tag.Set(MustParsePath("l[].a.b[0]"), NewIntTag(2)) }},
+	}
+
+	for _, test := range tests {
+		tag := mustSNBT(t, test.start)
+		n, err := test.op(&tag)
+		var me *ModifyError
+		if !errors.As(err, &me) || n != 0 {
+			t.Errorf("%s: Want ModifyError and no changes, have %d, %v", test.start, n, err)
+		}
+		if !Equal(tag, mustSNBT(t, test.start)) {
+			t.Errorf("%s: Failed operation changed the tag to %s", test.start, FormatSNBT(tag, SNBTOptions{}))
+		}
+	}
+}
+
+func TestModifyCopiesValue(t *testing.T) {
+	tag := mustSNBT(t, `{items:[{},{}]}`)
+	v := Tag{TAG_Compound, TagCompound{}}
+	if n, err := tag.Set(MustParsePath("items[].tag"), v); n != 2 || err != nil {
+		t.Fatalf("Want 2 changes, have %d (err: %v)", n, err)
+	}
+	if _, err := tag.Set(MustParsePath("items[0].tag.x"), NewByteTag(1)); err != nil {
+		t.Fatalf("Could not set: %s", err)
+	}
+	if len(v.Payload.(TagCompound)) != 0 || len(MustParsePath("items[1].tag.x").Get(tag)) != 0 {
+		t.Errorf("Set tags are not independent copies: %s", FormatSNBT(tag, SNBTOptions{}))
+	}
+}
@@ -0,0 +1,120 @@
+package nbt
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// StringEncoding is the encoding of strings (including tag names) in NBT data.
+type StringEncoding int
+
+// Valid StringEncoding values.
+const (
+	// ModifiedUTF8 is the encoding used by Java (and therefore Java Edition). It differs from UTF-8 in two ways:
+	// NUL is encoded as 0xC0 0x80 and characters outside the Basic Multilingual Plane are encoded as surrogate pairs,
+	// each surrogate taking three bytes. When reading, regular four byte UTF-8 sequences are accepted too.
+	ModifiedUTF8 StringEncoding = iota
+
+	// UTF8 is standard UTF-8, as used by Bedrock Edition. Invalid UTF-8 is rejected.
+	UTF8
+)
+
+var (
+	errInvalidMUTF8 = errors.New("String is not valid modified UTF-8")
+	errInvalidUTF8  = errors.New("String is not valid UTF-8")
+)
+
+// isPlainASCII checks, if s is encoded the same in UTF-8 and modified UTF-8.
+func isPlainASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == 0 || c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeMUTF8 encodes s as modified UTF-8. s must be valid UTF-8.
+func encodeMUTF8(s string) ([]byte, error) {
+	return appendMUTF8(make([]byte, 0, len(s)+len(s)/2), s)
+}
+
+// appendMUTF8 appends s encoded as modified UTF-8 to buf. s must be valid UTF-8.
+func appendMUTF8(buf []byte, s string) ([]byte, error) {
+	for i, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return nil, errInvalidUTF8
+			}
+			buf = appendMUTF8Char(buf, r)
+		case r == 0:
+			buf = append(buf, 0xc0, 0x80)
+		case r < utf8.RuneSelf:
+			buf = append(buf, byte(r))
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			buf = appendMUTF8Char(appendMUTF8Char(buf, r1), r2)
+		default:
+			buf = appendMUTF8Char(buf, r)
+		}
+	}
+	return buf, nil
+}
+
+// appendMUTF8Char appends a UTF-16 code unit in its two or three byte form.
+func appendMUTF8Char(buf []byte, r rune) []byte {
+	if r < 0x800 {
+		return append(buf, 0xc0|byte(r>>6), 0x80|byte(r&0x3f))
+	}
+	return append(buf, 0xe0|byte(r>>12), 0x80|byte(r>>6&0x3f), 0x80|byte(r&0x3f))
+}
+
+// decodeMUTF8 decodes modified UTF-8 data.
+func decodeMUTF8(b []byte) (string, error) {
+	plain := true
+	for _, c := range b {
+		if c == 0 || c >= utf8.RuneSelf {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return string(b), nil
+	}
+
+	var units []uint16
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xe0 == 0xc0:
+			if i+1 >= len(b) || b[i+1]&0xc0 != 0x80 {
+				return "", errInvalidMUTF8
+			}
+			units = append(units, uint16(c&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case c&0xf0 == 0xe0:
+			if i+2 >= len(b) || b[i+1]&0xc0 != 0x80 || b[i+2]&0xc0 != 0x80 {
+				return "", errInvalidMUTF8
+			}
+			units = append(units, uint16(c&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		case c&0xf8 == 0xf0:
+			// Standard UTF-8 for supplementary characters, written by many tools.
+			r, size := utf8.DecodeRune(b[i:])
+			if r == utf8.RuneError && size == 1 {
+				return "", errInvalidMUTF8
+			}
+			r1, r2 := utf16.EncodeRune(r)
+			units = append(units, uint16(r1), uint16(r2))
+			i += size
+		default:
+			return "", errInvalidMUTF8
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
@@ -0,0 +1,121 @@
+package nbt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMUTF8Encoding(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []byte
+	}{
+		{"abc", []byte("abc")},
+		{"a\x00b", []byte{'a', 0xc0, 0x80, 'b'}},
+		{"ÅÄÖ", []byte{0xc3, 0x85, 0xc3, 0x84, 0xc3, 0x96}},
+		{"€", []byte{0xe2, 0x82, 0xac}},
+		{"😀", []byte{0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := WritePayload(buf, NewStringTag(test.s)); err != nil {
+			t.Fatalf("Could not write %#v: %s", test.s, err)
+		}
+		want := append([]byte{0x00, byte(len(test.want))}, test.want...)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("Wrong encoding of %#v.\nWant: % x\nHave: % x", test.s, want, buf.Bytes())
+		}
+
+		tag, err := ReadPayload(buf, TAG_String)
+		if err != nil {
+			t.Fatalf("Could not read %#v: %s", test.s, err)
+		}
+		if s := tag.Payload.(string); s != test.s {
+			t.Errorf("Roundtrip changed %#v to %#v", test.s, s)
+		}
+	}
+}
+
+func TestMUTF8Compound(t *testing.T) {
+	comp := TagCompound{
+		"emoji 😀":    NewStringTag("🎉 party"),
+		"nul\x00key": NewStringTag("nul\x00value"),
+		"nested": Tag{TAG_Compound, TagCompound{
+			"list": NewListTag(TAG_String, []string{"\x00", "𝄞", ""}),
+		}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteNamedTag(buf, "root 🌍", Tag{TAG_Compound, comp}); err != nil {
+		t.Fatalf("Could not write NBT data: %s", err)
+	}
+	for _, b := range buf.Bytes() {
+		if b == 0xf0 {
+			t.Fatalf("Encoded data contains a four byte UTF-8 sequence")
+		}
+	}
+
+	tag, name, err := ReadNamedTag(buf)
+	if err != nil {
+		t.Fatalf("Could not read NBT data: %s", err)
+	}
+	if name != "root 🌍" {
+		t.Errorf("Wrong name %#v", name)
+	}
+	have := tag.Payload.(TagCompound)
+	if s, _ := have.GetString("emoji 😀"); s != "🎉 party" {
+		t.Errorf("Wrong value for emoji key: %#v", s)
+	}
+	if s, _ := have.GetString("nul\x00key"); s != "nul\x00value" {
+		t.Errorf("Wrong value for NUL key: %#v", s)
+	}
+	nested, _ := have.GetCompound("nested")
+	l, _ := nested.GetList("list")
+	for i, want := range []string{"\x00", "𝄞", ""} {
+		if s := l.Elems[i].(string); s != want {
+			t.Errorf("Wrong list element %d: %#v, expected %#v", i, s, want)
+		}
+	}
+}
+
+func TestMUTF8Decoding(t *testing.T) {
+	// Standard UTF-8 supplementary characters are accepted too.
+	tag, err := ReadPayload(bytes.NewReader([]byte{0x00, 0x04, 0xf0, 0x9f, 0x98, 0x80}), TAG_String)
+	if err != nil || tag.Payload.(string) != "😀" {
+		t.Errorf("Want \"😀\", have %v (err: %v)", tag.Payload, err)
+	}
+
+	for _, data := range [][]byte{{0x80}, {0xc3}, {0xe2, 0x82}, {0xff}} {
+		in := append([]byte{0x00, byte(len(data))}, data...)
+		if _, err := ReadPayload(bytes.NewReader(in), TAG_String); err == nil {
+			t.Errorf("Decoding % x should fail", data)
+		}
+	}
+}
+
+func TestStrictUTF8(t *testing.T) {
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.SetStringEncoding(UTF8)
+	if err := e.EncodePayload(NewStringTag("a\x00😀")); err != nil {
+		t.Fatalf("Could not write string: %s", err)
+	}
+	want := []byte{0x00, 0x06, 'a', 0x00, 0xf0, 0x9f, 0x98, 0x80}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Wrong encoding.\nWant: % x\nHave: % x", want, buf.Bytes())
+	}
+
+	if err := e.EncodePayload(NewStringTag("\xff")); err == nil {
+		t.Errorf("Encoding invalid UTF-8 should fail")
+	}
+	if err := NewEncoder(buf).EncodePayload(NewStringTag("\xff")); err == nil {
+		t.Errorf("Encoding invalid UTF-8 as modified UTF-8 should fail")
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte{0x00, 0x02, 0xc0, 0x80}))
+	d.SetStringEncoding(UTF8)
+	if _, err := d.DecodePayload(TAG_String); err == nil {
+		t.Errorf("Decoding modified UTF-8 NUL as strict UTF-8 should fail")
+	}
+}
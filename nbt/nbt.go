@@ -2,9 +2,7 @@
 package nbt
 
 import (
-	"encoding/binary"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"github.com/silvasur/kagus"
 	"io"
@@ -26,6 +24,7 @@ import (
 // 	TAG_List       -- TagList
 // 	TAG_Compound   -- TagCompound
 // 	TAG_Int_Array  -- []int32
+// 	TAG_Long_Array -- []int64
 type Tag struct {
 	Type    TagType
 	Payload interface{}
@@ -77,6 +76,14 @@ func (t Tag) String() string {
 			s += sep + fmt.Sprintf("%d", elem)
 			sep = ", "
 		}
+	case TAG_Long_Array:
+		l := t.Payload.([]int64)
+		s += ": "
+		sep := ""
+		for _, elem := range l {
+			s += sep + fmt.Sprintf("%d", elem)
+			sep = ", "
+		}
 	}
 
 	return s
@@ -85,222 +92,60 @@ func (t Tag) String() string {
 // TagCompund is the payload of a TAG_Compound. Initialize with make.
 type TagCompound map[string]Tag
 
-func readTagData(r io.Reader, tt TagType) (interface{}, error) {
-	switch tt {
-	case TAG_End:
-	case TAG_Byte:
-		var v uint8
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Short:
-		var v int16
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Int:
-		var v int32
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Long:
-		var v int64
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Float:
-		var v float32
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Double:
-		var v float64
-		err := binary.Read(r, binary.BigEndian, &v)
-		return v, err
-	case TAG_Byte_Array:
-		var l int32
-		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
-			return nil, err
-		}
-		if l < 0 {
-			return nil, errors.New("Byte array has negative length?")
-		}
-
-		data := make([]byte, l)
-		_, err := io.ReadFull(r, data)
-		return data, err
-	case TAG_String:
-		var l int16
-		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
-			return nil, err
-		}
-		if l < 0 {
-			return nil, errors.New("String has negative length?")
-		}
-
-		data := make([]byte, l)
-		_, err := io.ReadFull(r, data)
-		return string(data), err
-	case TAG_List:
-		_ltt, err := kagus.ReadByte(r)
-		if err != nil {
-			return nil, err
-		}
-		ltt := TagType(_ltt)
-
-		var l int32
-		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
-			return nil, err
-		}
-		if l < 0 {
-			return nil, errors.New("List has negative length?")
-		}
-
-		tl := TagList{Type: ltt, Elems: make([]interface{}, l)}
-		for i := 0; i < int(l); i++ {
-			if tl.Elems[i], err = readTagData(r, ltt); err != nil {
-				return nil, err
-			}
-		}
-		return tl, nil
-	case TAG_Compound:
-		comp := make(TagCompound)
-		for {
-			tag, name, err := ReadNamedTag(r)
-			if err != nil {
-				return nil, err
-			}
-			if tag.Type == TAG_End {
-				break
-			}
-			comp[name] = tag
-		}
-		return comp, nil
-	case TAG_Int_Array:
-		var l int32
-		if err := binary.Read(r, binary.BigEndian, &l); err != nil {
-			return nil, err
-		}
-		if l < 0 {
-			return nil, errors.New("Int Array has negative length?")
-		}
-
-		data := make([]int32, l)
-		for i := 0; i < int(l); i++ {
-			var e int32
-			if err := binary.Read(r, binary.BigEndian, &e); err != nil {
-				return nil, err
-			}
-			data[i] = e
-		}
-		return data, nil
-	}
-
-	return nil, errors.New("Unknown tag type")
-}
-
 // ReadNamedTag reads a named Tag from an io.Reader. It returns the Tag, the tags Name and an error.
+//
+// It reads no more data from r than necessary. Use a Decoder for more options.
 func ReadNamedTag(r io.Reader) (Tag, string, error) {
-	_tt, err := kagus.ReadByte(r)
-	if err != nil {
-		return Tag{}, "", err
-	}
-	tt := TagType(_tt)
-
-	if tt == TAG_End {
-		return Tag{Type: tt}, "", nil
-	}
-
-	name, err := readTagData(r, TAG_String)
-	if err != nil {
-		return Tag{}, "", err
-	}
-
-	td, err := readTagData(r, tt)
-	return Tag{Type: tt, Payload: td}, name.(string), err
+	return newDecoder(r).Decode()
 }
 
-func writeByte(w io.Writer, b byte) error {
-	_, err := w.Write([]byte{b})
-	return err
+// WriteNamedTag writes a named Tag to an io.Writer. Use an Encoder for more options.
+func WriteNamedTag(w io.Writer, name string, tag Tag) error {
+	return NewEncoder(w).Encode(name, tag)
 }
 
-func writeTagData(w io.Writer, tt TagType, data interface{}) error {
-	switch tt {
-	case TAG_End:
-		return nil
-	case TAG_Byte:
-		return writeByte(w, data.(byte))
-	case TAG_Short:
-		return binary.Write(w, binary.BigEndian, data.(int16))
-	case TAG_Int:
-		return binary.Write(w, binary.BigEndian, data.(int32))
-	case TAG_Long:
-		return binary.Write(w, binary.BigEndian, data.(int64))
-	case TAG_Float:
-		return binary.Write(w, binary.BigEndian, data.(float32))
-	case TAG_Double:
-		return binary.Write(w, binary.BigEndian, data.(float64))
-	case TAG_Byte_Array:
-		slice := data.([]byte)
-		if err := binary.Write(w, binary.BigEndian, int32(len(slice))); err != nil {
-			return err
-		}
-		_, err := w.Write(slice)
-		return err
-	case TAG_String:
-		strEnc := []byte(data.(string))
-		if err := binary.Write(w, binary.BigEndian, int16(len(strEnc))); err != nil {
-			return err
-		}
-		_, err := w.Write(strEnc)
-		return err
-	case TAG_List:
-		list := data.(TagList)
-		if err := writeByte(w, byte(list.Type)); err != nil {
-			return err
-		}
-
-		if err := binary.Write(w, binary.BigEndian, int32(len(list.Elems))); err != nil {
-			return err
-		}
-
-		for _, el := range list.Elems {
-			if err := writeTagData(w, list.Type, el); err != nil {
-				return err
-			}
-		}
-		return nil
-	case TAG_Compound:
-		comp := data.(TagCompound)
-		for name, tag := range comp {
-			if err := WriteNamedTag(w, name, tag); err != nil {
-				return err
-			}
-		}
-		return writeByte(w, TAG_End)
-	case TAG_Int_Array:
-		slice := data.([]int32)
-		if err := binary.Write(w, binary.BigEndian, int32(len(slice))); err != nil {
-			return err
-		}
+// ReadNamelessTag reads a root tag without a name, as used in network packets since Java Edition 1.20.2. NetworkLimits are enforced.
+// See Decoder.UseNamelessRoot for more info.
+func ReadNamelessTag(r io.Reader) (Tag, error) {
+	d := newDecoder(r)
+	d.UseNamelessRoot()
+	d.SetLimits(NetworkLimits)
+	tag, _, err := d.Decode()
+	return tag, err
+}
 
-		for _, el := range slice {
-			if err := binary.Write(w, binary.BigEndian, el); err != nil {
-				return err
-			}
-		}
+// WriteNamelessTag writes a root tag without a name. See ReadNamelessTag.
+func WriteNamelessTag(w io.Writer, tag Tag) error {
+	e := NewEncoder(w)
+	e.UseNamelessRoot()
+	return e.Encode("", tag)
+}
 
-		return nil
-	}
+// ReadPayload reads the payload of a tag of type tt, for data where the type is known in advance and no type or name is stored.
+func ReadPayload(r io.Reader, tt TagType) (Tag, error) {
+	return newDecoder(r).DecodePayload(tt)
+}
 
-	return errors.New("Unknown tage type")
+// ParseNamedTag decodes a named Tag from data, like ReadNamedTag. Use NewBytesDecoder for more options.
+func ParseNamedTag(data []byte) (Tag, string, error) {
+	return NewBytesDecoder(data).Decode()
 }
 
-// WriteNamedTag writes a named Tag to an io.Writer.
-func WriteNamedTag(w io.Writer, name string, tag Tag) error {
-	if err := writeByte(w, byte(tag.Type)); err != nil {
-		return err
-	}
+// ParseNamelessTag decodes a root tag without a name from data, like ReadNamelessTag.
+func ParseNamelessTag(data []byte) (Tag, error) {
+	d := NewBytesDecoder(data)
+	d.UseNamelessRoot()
+	d.SetLimits(NetworkLimits)
+	tag, _, err := d.Decode()
+	return tag, err
+}
 
-	if err := writeTagData(w, TAG_String, name); err != nil {
-		return err
-	}
+// ParsePayload decodes the payload of a tag of type tt from data, like ReadPayload.
+func ParsePayload(data []byte, tt TagType) (Tag, error) {
+	return NewBytesDecoder(data).DecodePayload(tt)
+}
 
-	return writeTagData(w, tag.Type, tag.Payload)
+// WritePayload writes the payload of a tag without its type and name. See ReadPayload.
+func WritePayload(w io.Writer, tag Tag) error {
+	return NewEncoder(w).EncodePayload(tag)
 }
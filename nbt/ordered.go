@@ -0,0 +1,58 @@
+package nbt
+
+import (
+	"sort"
+)
+
+// KeyOrder is the order of the compound entries in a tag, as read by Decoder.DecodeOrdered. Encoder.EncodeOrdered
+// writes a tag in this order, so data can be written back unchanged. The tag itself is unaffected, its compounds
+// are TagCompounds as usual.
+//
+// A KeyOrder mirrors the structure of its tag. For a compound, Names are the names of the entries in their order and
+// Children[i] is the KeyOrder of the entry Names[i]. For a list, Children[i] is the KeyOrder of the i-th element.
+// Children may be shorter than the entries or elements (or nil) and the KeyOrder of a tag without compounds is nil.
+//
+// Names must be unique. If the input contained a name more than once, only its first position is kept (the value is
+// the last one, as in Decode).
+type KeyOrder struct {
+	Names    []string
+	Children []*KeyOrder
+}
+
+// child returns the KeyOrder of the i-th entry or element. It is nil if o is nil.
+func (o *KeyOrder) child(i int) *KeyOrder {
+	if o == nil || i >= len(o.Children) {
+		return nil
+	}
+	return o.Children[i]
+}
+
+// setChild sets the KeyOrder of the i-th entry or element. Children is only allocated for a non-nil KeyOrder.
+func (o *KeyOrder) setChild(i int, c *KeyOrder) {
+	if c == nil {
+		if i < len(o.Children) {
+			o.Children[i] = nil
+		}
+		return
+	}
+	if i >= len(o.Children) {
+		o.Children = append(o.Children, make([]*KeyOrder, i+1-len(o.Children))...)
+	}
+	o.Children[i] = c
+}
+
+// extraNames returns the names of comp that are not in o, sorted.
+func (o *KeyOrder) extraNames(comp TagCompound) []string {
+	known := make(map[string]bool, len(o.Names))
+	for _, name := range o.Names {
+		known[name] = true
+	}
+	var names []string
+	for name := range comp {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,123 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSortKeys(t *testing.T) {
+	comp := make(TagCompound)
+	for _, k := range []string{"m", "b", "z", "a", "k", "c"} {
+		comp[k] = NewStringTag(k)
+	}
+	comp["nested"] = Tag{TAG_Compound, TagCompound{"y": NewByteTag(1), "x": NewByteTag(2)}}
+	tag := Tag{TAG_Compound, comp}
+
+	encode := func() []byte {
+		buf := new(bytes.Buffer)
+		e := NewEncoder(buf)
+		e.SetSortKeys(true)
+		if err := e.Encode("", tag); err != nil {
+			t.Fatalf("Could not encode: %s", err)
+		}
+		return buf.Bytes()
+	}
+
+	first := encode()
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(encode(), first) {
+			t.Fatalf("Encoding with sorted keys is not deterministic")
+		}
+	}
+
+	d := NewDecoder(bytes.NewReader(first))
+	_, _, order, err := d.DecodeOrdered()
+	if err != nil {
+		t.Fatalf("Could not decode: %s", err)
+	}
+	if want := []string{"a", "b", "c", "k", "m", "nested", "z"}; !reflect.DeepEqual(order.Names, want) {
+		t.Errorf("Wrong keys %v, expected %v", order.Names, want)
+	}
+	if want := []string{"x", "y"}; !reflect.DeepEqual(order.Children[5].Names, want) {
+		t.Errorf("Wrong nested keys %v, expected %v", order.Children[5].Names, want)
+	}
+}
+
+func TestDecodeOrderedRoundtrip(t *testing.T) {
+	zr, err := gzip.NewReader(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not decompress bigtest: %s", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Could not decompress bigtest: %s", err)
+	}
+
+	// Add a list of compounds, so KeyOrders of list elements are needed too.
+	raw = append(raw, TAG_List, 0x00, 0x01, 'l', TAG_Compound, 0x00, 0x00, 0x00, 0x02,
+		TAG_End,
+		TAG_Byte, 0x00, 0x01, 'b', 0x01,
+		TAG_Byte, 0x00, 0x01, 'a', 0x02,
+		TAG_Byte, 0x00, 0x01, 'c', 0x03,
+		TAG_End)
+
+	d := NewDecoder(bytes.NewReader(raw))
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	for {
+		tag, name, order, err := d.DecodeOrdered()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Could not decode: %s", err)
+		}
+		if _, ok := tag.Payload.(TagCompound); tag.Type == TAG_Compound && !ok {
+			t.Errorf("Want TagCompound payload, have %T", tag.Payload)
+		}
+		if err := e.EncodeOrdered(name, tag, order); err != nil {
+			t.Fatalf("Could not encode: %s", err)
+		}
+	}
+
+	if !bytes.Equal(buf.Bytes(), raw) {
+		t.Errorf("Read-then-write did not reproduce the input")
+	}
+}
+
+func TestKeyOrder(t *testing.T) {
+	data := []byte{TAG_Compound, 0x00, 0x00,
+		TAG_Byte, 0x00, 0x01, 'b', 0x01,
+		TAG_Byte, 0x00, 0x01, 'a', 0x02,
+		TAG_Byte, 0x00, 0x01, 'b', 0x03,
+		TAG_Compound, 0x00, 0x01, 'c', TAG_End,
+		TAG_End}
+	tag, _, order, err := NewBytesDecoder(data).DecodeOrdered()
+	if err != nil {
+		t.Fatalf("Could not decode: %s", err)
+	}
+	// A duplicate name keeps its first position and the last value.
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(order.Names, want) {
+		t.Errorf("Wrong keys %v, expected %v", order.Names, want)
+	}
+	if b, _ := tag.Payload.(TagCompound).GetByte("b"); b != 3 {
+		t.Errorf("Want last value of b, have %d", b)
+	}
+
+	// Changed tags are written in the order, new entries sorted after them.
+	comp := tag.Payload.(TagCompound)
+	delete(comp, "a")
+	comp["z"] = NewByteTag(4)
+	comp["y"] = NewByteTag(5)
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).EncodeOrdered("", tag, order); err != nil {
+		t.Fatalf("Could not encode: %s", err)
+	}
+	_, _, order, err = NewBytesDecoder(buf.Bytes()).DecodeOrdered()
+	if want := []string{"b", "c", "y", "z"}; err != nil || !reflect.DeepEqual(order.Names, want) {
+		t.Errorf("Wrong keys %v, expected %v (err %v)", order.Names, want, err)
+	}
+}
@@ -0,0 +1,371 @@
+package nbt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is an NBT path, as used by the /data command of Minecraft, e.g. `Inventory[{Slot:0b}].tag.display.Name`.
+//
+// The syntax of the elements is:
+//
+// 	name           -- The entry "name" of a compound. Names with special characters are quoted like SNBT strings.
+// 	name{filter}   -- The entry "name" of a compound, if it is a compound matching the SNBT compound filter.
+// 	[index]        -- The element of a list or array at index. Negative indices count from the end.
+// 	[]             -- All elements of a list or array.
+// 	[{filter}]     -- All compounds in a list matching filter.
+// 	{filter}       -- The root tag, if it is a compound matching filter. Only allowed at the beginning.
+//
+// Names are separated by dots, elements in brackets directly follow the previous element.
+//
+// A compound matches a filter, if it has all entries of the filter and they match. A list matches a list in a filter,
+// if it contains a match for every element of the filter list (an empty filter list matches only an empty list).
+// Other tags must be equal.
+type Path []PathElem
+
+// PathElemKind is the kind of a PathElem.
+type PathElemKind int
+
+// Valid PathElemKind values.
+const (
+	PathKey    PathElemKind = iota // `name` or `name{filter}`. Uses Name and Filter.
+	PathIndex                      // `[index]`. Uses Index.
+	PathAll                        // `[]`.
+	PathFilter                     // `[{filter}]`. Uses Filter.
+	PathRoot                       // `{filter}`. Uses Filter.
+)
+
+// PathElem is an element of a Path.
+type PathElem struct {
+	Kind   PathElemKind
+	Name   string
+	Index  int
+	Filter Tag // A TAG_Compound. For PathKey, a zero Tag means no filter.
+}
+
+// PathMatch is a tag found by Path.Get. Path is the concrete path of Tag, only consisting of names and non-negative indices.
+type PathMatch struct {
+	Path Path
+	Tag  Tag
+}
+
+// PathSyntaxError is returned by ParsePath for invalid paths.
+type PathSyntaxError struct {
+	Col int // Position of the error, starting at 1. Counts characters, not bytes.
+	Msg string
+}
+
+func (e *PathSyntaxError) Error() string {
+	return fmt.Sprintf("nbt path: col %d: %s", e.Col, e.Msg)
+}
+
+// ParsePath parses an NBT path (see Path).
+func ParsePath(s string) (Path, error) {
+	p := &snbtParser{s: s}
+	path, err := parsePath(p)
+	if err == nil && p.pos < len(p.s) {
+		err = p.errorf("unexpected trailing data")
+	}
+	if se, ok := err.(*SNBTSyntaxError); ok {
+		return nil, &PathSyntaxError{se.Col, se.Msg}
+	}
+	return path, err
+}
+
+// MustParsePath is like ParsePath, but panics, if the path can't be parsed. Useful for paths known at compile time.
+func MustParsePath(s string) Path {
+	path, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// parsePath parses a path, that ends at the end of input or a space.
+func parsePath(p *snbtParser) (Path, error) {
+	var path Path
+	for {
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("expected path element, got end of input")
+		}
+
+		var elem PathElem
+		var err error
+		switch c := p.s[p.pos]; {
+		case c == '{':
+			if len(path) > 0 {
+				return nil, p.errorf("filter without name")
+			}
+			elem.Kind = PathRoot
+			elem.Filter, err = p.parseCompound()
+		case c == '[':
+			elem, err = parsePathBrackets(p)
+		case c == '"' || c == '\'':
+			if elem.Name, err = p.parseQuoted(); err == nil {
+				err = parsePathKeyFilter(p, &elem)
+			}
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isUnquotedPathChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("expected path element")
+			}
+			elem.Name = p.s[start:p.pos]
+			err = parsePathKeyFilter(p, &elem)
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, elem)
+
+		if p.pos >= len(p.s) {
+			return path, nil
+		}
+		switch p.s[p.pos] {
+		case '.':
+			p.pos++
+		case '[':
+		case ' ':
+			return path, nil
+		default:
+			return nil, p.errorf("expected '.' or '['")
+		}
+	}
+}
+
+func parsePathKeyFilter(p *snbtParser, elem *PathElem) error {
+	if p.pos < len(p.s) && p.s[p.pos] == '{' {
+		var err error
+		elem.Filter, err = p.parseCompound()
+		return err
+	}
+	return nil
+}
+
+func parsePathBrackets(p *snbtParser) (PathElem, error) {
+	p.pos++ // [
+	if p.pos >= len(p.s) {
+		return PathElem{}, p.errorf("expected index, got end of input")
+	}
+
+	var elem PathElem
+	switch c := p.s[p.pos]; {
+	case c == ']':
+		elem.Kind = PathAll
+	case c == '{':
+		elem.Kind = PathFilter
+		var err error
+		if elem.Filter, err = p.parseCompound(); err != nil {
+			return PathElem{}, err
+		}
+	default:
+		start := p.pos
+		if c == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		i, err := strconv.ParseInt(p.s[start:p.pos], 10, 32)
+		if err != nil {
+			p.pos = start
+			return PathElem{}, p.errorf("invalid index")
+		}
+		elem.Kind = PathIndex
+		elem.Index = int(i)
+	}
+
+	if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+		return PathElem{}, p.errorf("expected ']'")
+	}
+	p.pos++
+	return elem, nil
+}
+
+func isUnquotedPathChar(c byte) bool {
+	switch c {
+	case ' ', '"', '\'', '[', ']', '.', '{', '}':
+		return false
+	}
+	return true
+}
+
+func formatPathKey(name string) string {
+	if name == "" {
+		return `""`
+	}
+	for i := 0; i < len(name); i++ {
+		if !isUnquotedPathChar(name[i]) {
+			return quoteSNBT(name)
+		}
+	}
+	return name
+}
+
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, elem := range p {
+		switch elem.Kind {
+		case PathKey:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(formatPathKey(elem.Name))
+			if elem.Filter.Type == TAG_Compound {
+				sb.WriteString(FormatSNBT(elem.Filter, SNBTOptions{}))
+			}
+		case PathIndex:
+			fmt.Fprintf(&sb, "[%d]", elem.Index)
+		case PathAll:
+			sb.WriteString("[]")
+		case PathFilter:
+			sb.WriteString("[" + FormatSNBT(elem.Filter, SNBTOptions{}) + "]")
+		case PathRoot:
+			sb.WriteString(FormatSNBT(elem.Filter, SNBTOptions{}))
+		}
+	}
+	return sb.String()
+}
+
+// Key returns a new Path with the compound entry name appended to p.
+func (p Path) Key(name string) Path {
+	return append(p[:len(p):len(p)], PathElem{Kind: PathKey, Name: name})
+}
+
+// Index returns a new Path with the list or array index i appended to p.
+func (p Path) Index(i int) Path {
+	return append(p[:len(p):len(p)], PathElem{Kind: PathIndex, Index: i})
+}
+
+// Get returns all tags in tag matching p, in the order they are found. Entries of a TagCompound have no defined order,
+// but a path can only match more than one tag by using `[]` or `[{filter}]`.
+func (p Path) Get(tag Tag) []PathMatch {
+	matches := []PathMatch{{Path{}, tag}}
+	for _, elem := range p {
+		var next []PathMatch
+		for _, m := range matches {
+			next = elem.appendMatches(next, m)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		matches = next
+	}
+	return matches
+}
+
+// appendMatches appends all children of m matching elem to matches.
+func (elem PathElem) appendMatches(matches []PathMatch, m PathMatch) []PathMatch {
+	switch elem.Kind {
+	case PathRoot:
+		if matchTag(elem.Filter, m.Tag) {
+			matches = append(matches, m)
+		}
+	case PathKey:
+		child, ok := compoundEntry(m.Tag, elem.Name)
+		if ok && (elem.Filter.Type != TAG_Compound || matchTag(elem.Filter, child)) {
+			matches = append(matches, PathMatch{m.Path.Key(elem.Name), child})
+		}
+	case PathIndex:
+		n := elemCount(m.Tag)
+		i := elem.Index
+		if i < 0 {
+			i += n
+		}
+		if i >= 0 && i < n {
+			matches = append(matches, PathMatch{m.Path.Index(i), elemAt(m.Tag, i)})
+		}
+	case PathAll, PathFilter:
+		n := elemCount(m.Tag)
+		for i := 0; i < n; i++ {
+			child := elemAt(m.Tag, i)
+			if elem.Kind == PathAll || matchTag(elem.Filter, child) {
+				matches = append(matches, PathMatch{m.Path.Index(i), child})
+			}
+		}
+	}
+	return matches
+}
+
+// compoundEntry returns the entry name of tag, if tag is a compound.
+func compoundEntry(tag Tag, name string) (Tag, bool) {
+	if tag.Type != TAG_Compound {
+		return Tag{}, false
+	}
+	child, ok := tag.Payload.(TagCompound)[name]
+	return child, ok
+}
+
+// elemCount returns the number of elements of a list or array and 0 for all other tags.
+func elemCount(tag Tag) int {
+	switch v := tag.Payload.(type) {
+	case TagList:
+		return len(v.Elems)
+	case []byte:
+		return len(v)
+	case []int32:
+		return len(v)
+	case []int64:
+		return len(v)
+	}
+	return 0
+}
+
+// elemAt returns the element i of a list or array. i must be less than elemCount(tag).
+func elemAt(tag Tag, i int) Tag {
+	switch v := tag.Payload.(type) {
+	case TagList:
+		return Tag{v.Type, v.Elems[i]}
+	case []byte:
+		return NewByteTag(v[i])
+	case []int32:
+		return NewIntTag(v[i])
+	case []int64:
+		return NewLongTag(v[i])
+	}
+	panic("not a list or array")
+}
+
+// matchTag checks, if tag matches filter (see Path).
+func matchTag(filter, tag Tag) bool {
+	if filter.Type != tag.Type {
+		return false
+	}
+
+	switch filter.Type {
+	case TAG_Compound:
+		if _, ok := tag.Payload.(TagCompound); !ok {
+			return false
+		}
+		for name, want := range filter.Payload.(TagCompound) {
+			have, ok := compoundEntry(tag, name)
+			if !ok || !matchTag(want, have) {
+				return false
+			}
+		}
+		return true
+	case TAG_List:
+		fl, ok1 := filter.Payload.(TagList)
+		tl, ok2 := tag.Payload.(TagList)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if len(fl.Elems) == 0 {
+			return len(tl.Elems) == 0
+		}
+	outer:
+		for _, want := range fl.Elems {
+			for _, have := range tl.Elems {
+				if matchTag(Tag{fl.Type, want}, Tag{tl.Type, have}) {
+					continue outer
+				}
+			}
+			return false
+		}
+		return true
+	}
+	return Equal(filter, tag)
+}
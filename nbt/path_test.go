@@ -0,0 +1,130 @@
+package nbt
+
+import (
+	"math"
+	"testing"
+)
+
+func pathTestTag(t *testing.T) Tag {
+	tag, err := ParseSNBT(`{
+		Inventory: [
+			{Slot: 0b, id: "minecraft:stone", Count: 64b},
+			{Slot: 1b, id: "minecraft:diamond_sword", Count: 1b, tag: {display: {Name: '"Excalibur"'}, Enchantments: [{id: "sharpness", lvl: 5s}, {id: "unbreaking", lvl: 3s}]}},
+			{Slot: 5b, id: "minecraft:stick", Count: 1b, tag: {display: {Name: '"Stick"'}}}
+		],
+		Pos: [1.5d, 64.0d, -3.25d],
+		"weird.key": {"a b": [I; 1, 2, 3]},
+		ActiveEffects: []
+	}`)
+	if err != nil {
+		t.Fatalf("Could not parse test data: %s", err)
+	}
+	return tag
+}
+
+func TestPathGet(t *testing.T) {
+	tag := pathTestTag(t)
+
+	tests := []struct {
+		path  string
+		paths []string
+		want  []Tag
+	}{
+		{"Inventory[{Slot:1b}].tag.display.Name", []string{"Inventory[1].tag.display.Name"}, []Tag{NewStringTag(`"Excalibur"`)}},
+		{"Inventory[0].id", []string{"Inventory[0].id"}, []Tag{NewStringTag("minecraft:stone")}},
+		{"Inventory[-1].Slot", []string{"Inventory[2].Slot"}, []Tag{NewByteTag(5)}},
+		{"Inventory[].Count", []string{"Inventory[0].Count", "Inventory[1].Count", "Inventory[2].Count"}, []Tag{NewByteTag(64), NewByteTag(1), NewByteTag(1)}},
+		{"Inventory[{Count:1b}].tag.display.Name", []string{"Inventory[1].tag.display.Name", "Inventory[2].tag.display.Name"}, []Tag{NewStringTag(`"Excalibur"`), NewStringTag(`"Stick"`)}},
+		{"Inventory[].tag.Enchantments[{id:\"unbreaking\"}].lvl", []string{"Inventory[1].tag.Enchantments[1].lvl"}, []Tag{NewShortTag(3)}},
+		{"Inventory[{tag:{Enchantments:[{id:\"sharpness\"}]}}].Slot", []string{"Inventory[1].Slot"}, []Tag{NewByteTag(1)}},
+		{"Inventory[].tag{display:{}}.display.Name", []string{"Inventory[1].tag.display.Name", "Inventory[2].tag.display.Name"}, []Tag{NewStringTag(`"Excalibur"`), NewStringTag(`"Stick"`)}},
+		{"Pos[1]", []string{"Pos[1]"}, []Tag{NewDoubleTag(64)}},
+		{`"weird.key".'a b'[-3]`, []string{`"weird.key"."a b"[0]`}, []Tag{NewIntTag(1)}},
+		{"{ActiveEffects:[]}.Pos[0]", []string{"Pos[0]"}, []Tag{NewDoubleTag(1.5)}},
+		{"{ActiveEffects:[{}]}.Pos[0]", nil, nil},
+		{"Inventory[3]", nil, nil},
+		{"Inventory[-4]", nil, nil},
+		{"Inventory[{Slot:1}]", nil, nil}, // 1 is an int, Slot a byte
+		{"Pos.x", nil, nil},
+		{"Missing[]", nil, nil},
+	}
+
+	for _, test := range tests {
+		path, err := ParsePath(test.path)
+		if err != nil {
+			t.Errorf("%s: Could not parse: %s", test.path, err)
+			continue
+		}
+		matches := path.Get(tag)
+		if len(matches) != len(test.want) {
+			t.Errorf("%s: Want %d matches, have %d", test.path, len(test.want), len(matches))
+			continue
+		}
+		for i, m := range matches {
+			if s := m.Path.String(); s != test.paths[i] {
+				t.Errorf("%s: Match %d: want path %s, have %s", test.path, i, test.paths[i], s)
+			}
+			if m.Tag.Type != test.want[i].Type || m.Tag.Payload != test.want[i].Payload {
+				t.Errorf("%s: Match %d: want %s, have %s", test.path, i, test.want[i], m.Tag)
+			}
+		}
+	}
+}
+
+func TestPathString(t *testing.T) {
+	for _, s := range []string{
+		"a.b.c",
+		"Inventory[{Slot:0b}].tag.display.Name",
+		"{id:\"minecraft:pig\"}.Passengers[].id",
+		"list[-1][]",
+		"item{Count:1b}.id",
+		`"weird.key"."a b"[0]`,
+		`""`,
+	} {
+		path, err := ParsePath(s)
+		if err != nil {
+			t.Errorf("%s: Could not parse: %s", s, err)
+			continue
+		}
+		if have := path.String(); have != s {
+			t.Errorf("%s: String returned %s", s, have)
+		}
+	}
+}
+
+func TestPathSyntaxErrors(t *testing.T) {
+	tests := map[string]int{
+		"":               1,
+		"a.":             3,
+		"a..b":           3,
+		"a[":             3,
+		"a[x]":           3,
+		"a[1":            4,
+		"a]":             2,
+		"a.{b:1}":        3,
+		"a[{b:}]":        6,
+		"a b":            2,
+		`"unclosed`:      10,
+		"a[99999999999]": 3,
+	}
+	for s, col := range tests {
+		_, err := ParsePath(s)
+		se, ok := err.(*PathSyntaxError)
+		if !ok {
+			t.Errorf("%q: Want PathSyntaxError, have %v", s, err)
+			continue
+		}
+		if se.Col != col {
+			t.Errorf("%q: Want error at col %d, have %s", s, col, se)
+		}
+	}
+}
+
+func TestPathFilterNaN(t *testing.T) {
+	nan := Tag{TAG_Compound, TagCompound{"v": NewDoubleTag(math.NaN())}}
+	tag := Tag{TAG_Compound, TagCompound{"l": NewListTag(TAG_Compound, []interface{}{nan.Clone().Payload})}}
+	p := Path{{Kind: PathKey, Name: "l"}, {Kind: PathFilter, Filter: nan}}
+	if m := p.Get(tag); len(m) != 1 {
+		t.Errorf("NaN filter should match, have %v", m)
+	}
+}
@@ -0,0 +1,308 @@
+// Package region reads and writes Minecraft region files (.mca / .mcr).
+//
+// A region file holds up to 32x32 chunks, each stored as a compressed NBT root tag. The file is divided into sectors of 4 KiB.
+// The first sector holds the location (offset and size in sectors) of every chunk, the second one the time of its last modification.
+package region
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/silvasur/gonbt/nbt"
+)
+
+// SectorSize is the size of a sector of a region file in bytes.
+const SectorSize = 4096
+
+const (
+	headerSize = 2 * SectorSize
+	maxSectors = 255 // A location entry stores the sector count in one byte.
+)
+
+// Compression ids used in the chunk header. See also nbt.CompressionByID.
+const (
+	CompressionGzip   = nbt.GzipID
+	CompressionZlib   = nbt.ZlibID
+	CompressionNone   = nbt.NoCompressionID
+	CompressionLZ4    = nbt.LZ4ID
+	CompressionCustom = nbt.CustomCompressionID // Followed by the name of the compression as a NBT string.
+
+	externalFlag = 0x80 // Chunk is stored in a separate .mcc file.
+)
+
+// Errors returned by a Region.
+var (
+	ErrNoChunk       = errors.New("region: chunk not present")
+	ErrChunkTooLarge = errors.New("region: chunk does not fit into 255 sectors")
+	ErrExternalChunk = errors.New("region: chunk is stored in an external file, which is not supported")
+	ErrCorrupt       = errors.New("region: corrupt region file")
+)
+
+// File is the storage of a region file. *os.File implements it.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Region is an opened region file.
+type Region struct {
+	f      File
+	closer io.Closer
+	comp   nbt.Compression
+
+	locations  [1024]uint32
+	timestamps [1024]uint32
+}
+
+// New reads the header of the region file f. If f is empty, an empty header is written.
+func New(f File) (*Region, error) {
+	r := &Region{f: f, comp: nbt.Zlib}
+
+	header := make([]byte, headerSize)
+	n, err := f.ReadAt(header, 0)
+	switch {
+	case n == 0 && err == io.EOF:
+		if _, err := f.WriteAt(header, 0); err != nil {
+			return nil, err
+		}
+		return r, nil
+	case n < headerSize:
+		if err == io.EOF {
+			err = ErrCorrupt
+		}
+		return nil, err
+	}
+
+	for i := range r.locations {
+		r.locations[i] = binary.BigEndian.Uint32(header[4*i:])
+		r.timestamps[i] = binary.BigEndian.Uint32(header[SectorSize+4*i:])
+	}
+	return r, nil
+}
+
+// Open opens the region file at path. The file is created, if it does not exist.
+func Open(path string) (*Region, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := New(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Close closes the underlying file, if the Region was opened with Open.
+func (r *Region) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// SetCompression sets the compression used by WriteChunk. The default is nbt.Zlib. Custom compressions (with id
+// nbt.CustomCompressionID) must be registered with nbt.RegisterCompression to be read back.
+func (r *Region) SetCompression(c nbt.Compression) {
+	if c == nil {
+		c = nbt.NoCompression
+	}
+	r.comp = c
+}
+
+// chunkIndex returns the index of a chunk in the header. x and z are chunk coordinates, either relative to the region or
+// absolute world coordinates.
+func chunkIndex(x, z int) int {
+	return (x & 31) + (z&31)*32
+}
+
+func (r *Region) location(i int) (offset, count int) {
+	loc := r.locations[i]
+	return int(loc >> 8), int(loc & 0xff)
+}
+
+// ChunkExists checks, if the chunk at x, z is present.
+func (r *Region) ChunkExists(x, z int) bool {
+	return r.locations[chunkIndex(x, z)] != 0
+}
+
+// Timestamp returns the time the chunk at x, z was last written.
+func (r *Region) Timestamp(x, z int) time.Time {
+	return time.Unix(int64(r.timestamps[chunkIndex(x, z)]), 0)
+}
+
+// ReadChunk reads the root tag of the chunk at x, z.
+func (r *Region) ReadChunk(x, z int) (nbt.Tag, error) {
+	offset, count := r.location(chunkIndex(x, z))
+	if offset == 0 {
+		return nbt.Tag{}, ErrNoChunk
+	}
+	if offset < 2 {
+		return nbt.Tag{}, ErrCorrupt
+	}
+
+	var head [5]byte
+	if _, err := r.f.ReadAt(head[:], int64(offset)*SectorSize); err != nil {
+		return nbt.Tag{}, err
+	}
+	length := int(binary.BigEndian.Uint32(head[:4]))
+	if length < 1 || length+4 > count*SectorSize {
+		return nbt.Tag{}, ErrCorrupt
+	}
+
+	id := head[4]
+	if id&externalFlag != 0 {
+		return nbt.Tag{}, ErrExternalChunk
+	}
+
+	data := make([]byte, length-1)
+	if _, err := r.f.ReadAt(data, int64(offset)*SectorSize+5); err != nil {
+		return nbt.Tag{}, err
+	}
+
+	c, data, err := chunkCompression(id, data)
+	if err != nil {
+		return nbt.Tag{}, err
+	}
+
+	d := nbt.NewDecoder(bytes.NewReader(data))
+	d.SetCompression(c)
+	tag, _, err := d.Decode()
+	return tag, err
+}
+
+// chunkCompression returns the compression of chunk data with compression id and the data following the header of a
+// custom compression.
+func chunkCompression(id byte, data []byte) (nbt.Compression, []byte, error) {
+	if id != CompressionCustom {
+		c, ok := nbt.CompressionByID(id)
+		if !ok {
+			return nil, nil, fmt.Errorf("region: unknown compression id %d", id)
+		}
+		return c, data, nil
+	}
+
+	if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+		return nil, nil, ErrCorrupt
+	}
+	n := 2 + int(binary.BigEndian.Uint16(data))
+	name := string(data[2:n])
+	c, ok := nbt.CustomCompressionByName(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("region: unknown custom compression %q", name)
+	}
+	return c, data[n:], nil
+}
+
+// WriteChunk writes tag as the root tag of the chunk at x, z, replacing an existing chunk.
+//
+// The chunk stays at its old position, if it still fits there. Otherwise it is written to the first free space large enough,
+// or appended to the file.
+func (r *Region) WriteChunk(x, z int, tag nbt.Tag) error {
+	buf := new(bytes.Buffer)
+	buf.Write([]byte{0, 0, 0, 0, r.comp.ID()})
+	if r.comp.ID() == CompressionCustom {
+		name := r.comp.String()
+		if len(name) > math.MaxUint16 {
+			return fmt.Errorf("region: name of compression %q is too long", name)
+		}
+		binary.Write(buf, binary.BigEndian, uint16(len(name)))
+		buf.WriteString(name)
+	}
+	e := nbt.NewEncoder(buf)
+	e.SetCompression(r.comp)
+	if err := e.Encode("", tag); err != nil {
+		return err
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+
+	count := (len(data) + SectorSize - 1) / SectorSize
+	if count > maxSectors {
+		return ErrChunkTooLarge
+	}
+	if pad := count*SectorSize - len(data); pad > 0 {
+		data = append(data, make([]byte, pad)...)
+	}
+
+	i := chunkIndex(x, z)
+	offset, oldCount := r.location(i)
+	if offset == 0 || count > oldCount {
+		offset = r.allocate(i, count)
+	}
+
+	if _, err := r.f.WriteAt(data, int64(offset)*SectorSize); err != nil {
+		return err
+	}
+	return r.setHeader(i, uint32(offset<<8|count), uint32(time.Now().Unix()))
+}
+
+// allocate finds space for count sectors for chunk i. The old sectors of chunk i are considered free.
+func (r *Region) allocate(i, count int) int {
+	var used []bool
+	for j := range r.locations {
+		if j == i {
+			continue
+		}
+		offset, n := r.location(j)
+		for s := offset; s < offset+n; s++ {
+			for len(used) <= s {
+				used = append(used, false)
+			}
+			used[s] = true
+		}
+	}
+
+	free := 0
+	for s := 2; s < len(used); s++ {
+		if used[s] {
+			free = 0
+			continue
+		}
+		free++
+		if free == count {
+			return s - count + 1
+		}
+	}
+
+	// Append to the end, reusing trailing free sectors.
+	if len(used) < 2 {
+		return 2
+	}
+	return len(used) - free
+}
+
+func (r *Region) setHeader(i int, location, timestamp uint32) error {
+	var b [4]byte
+
+	binary.BigEndian.PutUint32(b[:], location)
+	if _, err := r.f.WriteAt(b[:], int64(4*i)); err != nil {
+		return err
+	}
+	r.locations[i] = location
+
+	binary.BigEndian.PutUint32(b[:], timestamp)
+	if _, err := r.f.WriteAt(b[:], int64(SectorSize+4*i)); err != nil {
+		return err
+	}
+	r.timestamps[i] = timestamp
+	return nil
+}
+
+// DeleteChunk removes the chunk at x, z. Its sectors will be reused by later writes.
+func (r *Region) DeleteChunk(x, z int) error {
+	return r.setHeader(chunkIndex(x, z), 0, 0)
+}
@@ -0,0 +1,265 @@
+package region
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/silvasur/gonbt/nbt"
+)
+
+// memFile is an in-memory File.
+type memFile struct {
+	data []byte
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func testChunk(x, z int32, payload int) nbt.Tag {
+	// Random data does not compress, so payload controls the size of the chunk.
+	data := make([]byte, payload)
+	rand.New(rand.NewSource(int64(x*32 + z))).Read(data)
+
+	level := nbt.TagCompound{
+		"xPos": nbt.NewIntTag(x),
+		"zPos": nbt.NewIntTag(z),
+		"data": nbt.NewByteArrayTag(data),
+	}
+	return nbt.Tag{Type: nbt.TAG_Compound, Payload: nbt.TagCompound{"Level": {Type: nbt.TAG_Compound, Payload: level}}}
+}
+
+func checkChunk(t *testing.T, r *Region, x, z int32, payload int) {
+	tag, err := r.ReadChunk(int(x), int(z))
+	if err != nil {
+		t.Fatalf("Could not read chunk %d, %d: %s", x, z, err)
+	}
+
+	level, err := tag.Payload.(nbt.TagCompound).GetCompound("Level")
+	if err != nil {
+		t.Fatalf("Chunk %d, %d: Could not get Level: %s", x, z, err)
+	}
+	if xPos, _ := level.GetInt("xPos"); xPos != x {
+		t.Errorf("Chunk %d, %d: wrong xPos %d", x, z, xPos)
+	}
+	if zPos, _ := level.GetInt("zPos"); zPos != z {
+		t.Errorf("Chunk %d, %d: wrong zPos %d", x, z, zPos)
+	}
+	want := testChunk(x, z, payload).Payload.(nbt.TagCompound)["Level"].Payload.(nbt.TagCompound)["data"].Payload.([]byte)
+	if data, _ := level.GetByteArray("data"); !bytes.Equal(data, want) {
+		t.Errorf("Chunk %d, %d: wrong data", x, z)
+	}
+}
+
+// checkNoOverlap verifies that the sectors of the chunks do not overlap each other or the header.
+func checkNoOverlap(t *testing.T, r *Region) {
+	owner := make(map[int]int)
+	for i := range r.locations {
+		offset, count := r.location(i)
+		if offset == 0 {
+			continue
+		}
+		if offset < 2 {
+			t.Errorf("Chunk %d overlaps the header", i)
+		}
+		for s := offset; s < offset+count; s++ {
+			if o, ok := owner[s]; ok {
+				t.Errorf("Chunks %d and %d share sector %d", o, i, s)
+			}
+			owner[s] = i
+		}
+	}
+}
+
+func TestRegionWriteRead(t *testing.T) {
+	f := new(memFile)
+	r, err := New(f)
+	if err != nil {
+		t.Fatalf("Could not create region: %s", err)
+	}
+	if len(f.data) != headerSize {
+		t.Fatalf("New region file has size %d, expected %d", len(f.data), headerSize)
+	}
+
+	sizes := map[[2]int32]int{{0, 0}: 100, {1, 0}: 5000, {31, 31}: 20000, {-1, 2}: 9000}
+	for pos, size := range sizes {
+		if err := r.WriteChunk(int(pos[0]), int(pos[1]), testChunk(pos[0], pos[1], size)); err != nil {
+			t.Fatalf("Could not write chunk %v: %s", pos, err)
+		}
+	}
+	checkNoOverlap(t, r)
+
+	// Grow one chunk, shrink another one and delete a third.
+	sizes[[2]int32{0, 0}] = 30000
+	if err := r.WriteChunk(0, 0, testChunk(0, 0, 30000)); err != nil {
+		t.Fatalf("Could not grow chunk: %s", err)
+	}
+	sizes[[2]int32{31, 31}] = 10
+	r.SetCompression(nbt.NoCompression)
+	if err := r.WriteChunk(31, 31, testChunk(31, 31, 10)); err != nil {
+		t.Fatalf("Could not shrink chunk: %s", err)
+	}
+	delete(sizes, [2]int32{1, 0})
+	if err := r.DeleteChunk(1, 0); err != nil {
+		t.Fatalf("Could not delete chunk: %s", err)
+	}
+	checkNoOverlap(t, r)
+
+	if len(f.data)%SectorSize != 0 {
+		t.Errorf("Region file size %d is not a multiple of the sector size", len(f.data))
+	}
+
+	// Reopen to check the header was written correctly.
+	r, err = New(f)
+	if err != nil {
+		t.Fatalf("Could not reopen region: %s", err)
+	}
+	for pos, size := range sizes {
+		checkChunk(t, r, pos[0], pos[1], size)
+		if r.Timestamp(int(pos[0]), int(pos[1])).IsZero() {
+			t.Errorf("Chunk %v has no timestamp", pos)
+		}
+	}
+	if r.ChunkExists(1, 0) {
+		t.Errorf("Deleted chunk still exists")
+	}
+	if _, err := r.ReadChunk(1, 0); err != ErrNoChunk {
+		t.Errorf("Reading deleted chunk: want ErrNoChunk, have %v", err)
+	}
+	if _, err := r.ReadChunk(5, 5); err != ErrNoChunk {
+		t.Errorf("Reading missing chunk: want ErrNoChunk, have %v", err)
+	}
+}
+
+// testCompression is a custom compression, that stores zlib data.
+type testCompression struct{}
+
+func (testCompression) ID() byte                                 { return nbt.CustomCompressionID }
+func (testCompression) String() string                           { return "test:zlib" }
+func (testCompression) NewReader(r io.Reader) (io.Reader, error) { return nbt.Zlib.NewReader(r) }
+func (testCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nbt.Zlib.NewWriter(w)
+}
+
+func TestRegionCompressions(t *testing.T) {
+	nbt.RegisterCompression(testCompression{})
+
+	f := new(memFile)
+	r, _ := New(f)
+	comps := []nbt.Compression{nbt.Gzip, nbt.ZlibLevel(9), nbt.NoCompression, nbt.LZ4, testCompression{}}
+	for i, c := range comps {
+		r.SetCompression(c)
+		if err := r.WriteChunk(i, 0, testChunk(int32(i), 0, 1000)); err != nil {
+			t.Fatalf("%s: Could not write chunk: %s", c, err)
+		}
+		offset, _ := r.location(i)
+		if id := f.data[offset*SectorSize+4]; id != c.ID() {
+			t.Errorf("%s: Wrong compression id %d", c, id)
+		}
+	}
+	for i := range comps {
+		checkChunk(t, r, int32(i), 0, 1000)
+	}
+
+	offset, _ := r.location(len(comps) - 1)
+	f.data[offset*SectorSize+8] = 'x' // Change the name of the custom compression.
+	if _, err := r.ReadChunk(len(comps)-1, 0); err == nil {
+		t.Errorf("Unknown custom compression should fail")
+	}
+}
+
+func TestRegionReuseSectors(t *testing.T) {
+	f := new(memFile)
+	r, _ := New(f)
+
+	r.WriteChunk(0, 0, testChunk(0, 0, 3*SectorSize))
+	r.WriteChunk(1, 0, testChunk(1, 0, 100))
+	size := len(f.data)
+
+	r.DeleteChunk(0, 0)
+	if err := r.WriteChunk(2, 0, testChunk(2, 0, 2*SectorSize)); err != nil {
+		t.Fatalf("Could not write chunk: %s", err)
+	}
+	if len(f.data) != size {
+		t.Errorf("Free sectors were not reused: file grew from %d to %d bytes", size, len(f.data))
+	}
+	checkNoOverlap(t, r)
+	checkChunk(t, r, 1, 0, 100)
+	checkChunk(t, r, 2, 0, 2*SectorSize)
+}
+
+func TestRegionErrors(t *testing.T) {
+	f := new(memFile)
+	r, _ := New(f)
+
+	if err := r.WriteChunk(0, 0, testChunk(0, 0, 256*SectorSize)); err != ErrChunkTooLarge {
+		t.Errorf("Want ErrChunkTooLarge, have %v", err)
+	}
+
+	r.WriteChunk(0, 0, testChunk(0, 0, 10))
+	offset, _ := r.location(0)
+
+	f.data[offset*SectorSize+4] = CompressionZlib | externalFlag
+	if _, err := r.ReadChunk(0, 0); err != ErrExternalChunk {
+		t.Errorf("Want ErrExternalChunk, have %v", err)
+	}
+
+	f.data[offset*SectorSize+4] = 42
+	if _, err := r.ReadChunk(0, 0); err == nil {
+		t.Errorf("Unknown compression should fail")
+	}
+
+	binary.BigEndian.PutUint32(f.data[offset*SectorSize:], 2*SectorSize)
+	if _, err := r.ReadChunk(0, 0); err != ErrCorrupt {
+		t.Errorf("Want ErrCorrupt for oversized length, have %v", err)
+	}
+
+	if _, err := New(&memFile{make([]byte, 100)}); err != ErrCorrupt {
+		t.Errorf("Want ErrCorrupt for truncated header, have %v", err)
+	}
+}
+
+func TestRegionOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "r.0.0.mca")
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Could not open region: %s", err)
+	}
+	if err := r.WriteChunk(3, 4, testChunk(3, 4, 500)); err != nil {
+		t.Fatalf("Could not write chunk: %s", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Could not close region: %s", err)
+	}
+
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 3*SectorSize {
+		t.Fatalf("Region file has wrong size (err: %v)", err)
+	}
+
+	r, err = Open(path)
+	if err != nil {
+		t.Fatalf("Could not reopen region: %s", err)
+	}
+	defer r.Close()
+	checkChunk(t, r, 3, 4, 500)
+}
@@ -0,0 +1,185 @@
+package nbt
+
+// pathState is a position in a path: path[i:] remains to be matched at a tag.
+type pathState struct {
+	path Path
+	i    int
+}
+
+// needsTag checks, if the tag must be decoded to continue matching: if the path is complete or a filter must be checked.
+func (s pathState) needsTag() bool {
+	if s.i == len(s.path) || s.path[s.i].Kind == PathRoot {
+		return true
+	}
+	if s.i > 0 {
+		prev := s.path[s.i-1]
+		return prev.Kind == PathFilter || (prev.Kind == PathKey && prev.Filter.Type == TAG_Compound)
+	}
+	return false
+}
+
+// appendMatches appends the matches of the rest of the path within tag, which has the concrete path prefix.
+func (s pathState) appendMatches(matches []PathMatch, prefix Path, tag Tag) []PathMatch {
+	if s.i > 0 {
+		if prev := s.path[s.i-1]; prev.Kind == PathFilter || (prev.Kind == PathKey && prev.Filter.Type == TAG_Compound) {
+			if !matchTag(prev.Filter, tag) {
+				return matches
+			}
+		}
+	}
+
+	for _, m := range s.path[s.i:].Get(tag) {
+		path := append(prefix[:len(prefix):len(prefix)], m.Path...)
+		matches = append(matches, PathMatch{path, m.Tag})
+	}
+	return matches
+}
+
+// DecodePaths reads the next root tag from the stream like Decode, but only decodes the tags matching one of paths (see
+// Path.Get). Everything else is skipped without decoding it, which is much faster and allocates no memory. It returns
+// the matches in the order of the stream and the name of the root tag.
+//
+// A tag matching several paths is returned once for each path. If a path element has a filter, the tags it is checked
+// against are decoded completely.
+func (d *Decoder) DecodePaths(paths ...Path) ([]PathMatch, string, error) {
+	tok, err := d.t.Next()
+	if err != nil {
+		return nil, "", err
+	}
+
+	matches, err := d.selectRoot(tok, paths)
+	return matches, tok.Name, err
+}
+
+func (d *Decoder) selectRoot(tok Token, paths []Path) ([]PathMatch, error) {
+	states := make([]pathState, len(paths))
+	for i, p := range paths {
+		states[i] = pathState{p, 0}
+	}
+
+	var matches []PathMatch
+	if err := d.selectTag(tok, states, &matches); err != nil {
+		d.t.abort()
+		return nil, err
+	}
+	return matches, nil
+}
+
+// selectTag reads the tag started by tok, decoding only the parts matching states.
+func (d *Decoder) selectTag(tok Token, states []pathState, matches *[]PathMatch) error {
+	t := &d.t
+
+	needsTag := false
+	for _, s := range states {
+		if s.needsTag() {
+			needsTag = true
+		}
+	}
+	if needsTag || (tok.Kind == TokenArrayStart && len(states) > 0) {
+		prefix := append(Path{}, t.Path()...)
+		td, err := d.readTagData(tok)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			*matches = s.appendMatches(*matches, prefix, Tag{tok.Type, td})
+		}
+		return nil
+	}
+
+	switch tok.Kind {
+	case TokenArrayStart:
+		return t.skipArray()
+	case TokenCompoundStart:
+		return d.selectEntries(states, matches)
+	case TokenListStart:
+		return d.selectElems(tok.Len, states, matches)
+	}
+	return nil
+}
+
+func (d *Decoder) selectEntries(states []pathState, matches *[]PathMatch) error {
+	t := &d.t
+	var child []pathState
+	for {
+		t.path = t.path[:t.pathKeep]
+		tt, raw, err := t.readRawHeader()
+		if err != nil {
+			return t.fail(TAG_Compound, err)
+		}
+		if tt == TAG_End {
+			t.end()
+			return nil
+		}
+
+		child = child[:0]
+		for _, s := range states {
+			if e := s.path[s.i]; e.Kind == PathKey && t.rawNameEquals(raw, e.Name) {
+				child = append(child, pathState{s.path, s.i + 1})
+			}
+		}
+		if len(child) == 0 {
+			if err := t.skipPayload(tt, len(t.stack)); err != nil {
+				return t.fail(TAG_Compound, err)
+			}
+			continue
+		}
+
+		name, err := t.decodeString(raw)
+		if err != nil {
+			return t.fail(tt, err)
+		}
+		base := len(t.path)
+		t.path = append(t.path, PathElem{Name: name})
+		tok, err := t.element(tt, name, base)
+		if err != nil {
+			return err
+		}
+		if err := d.selectTag(tok, append([]pathState(nil), child...), matches); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *Decoder) selectElems(n int, states []pathState, matches *[]PathMatch) error {
+	t := &d.t
+	var child []pathState
+	for i := 0; i < n; i++ {
+		t.path = t.path[:t.pathKeep]
+		f := &t.stack[len(t.stack)-1]
+		f.left--
+		f.index++
+
+		child = child[:0]
+		for _, s := range states {
+			e := s.path[s.i]
+			index := e.Index
+			if index < 0 {
+				index += n
+			}
+			if e.Kind == PathAll || e.Kind == PathFilter || (e.Kind == PathIndex && index == i) {
+				child = append(child, pathState{s.path, s.i + 1})
+			}
+		}
+		if len(child) == 0 {
+			if err := t.skipPayload(f.elemType, len(t.stack)); err != nil {
+				return t.fail(TAG_List, err)
+			}
+			continue
+		}
+
+		base := len(t.path)
+		t.path = append(t.path, PathElem{Kind: PathIndex, Index: i})
+		tok, err := t.element(f.elemType, "", base)
+		if err != nil {
+			return err
+		}
+		if err := d.selectTag(tok, append([]pathState(nil), child...), matches); err != nil {
+			return err
+		}
+	}
+
+	t.path = t.path[:t.pathKeep]
+	t.end()
+	return nil
+}
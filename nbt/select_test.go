@@ -0,0 +1,173 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func selectTestData(t *testing.T, network bool) []byte {
+	level := mustSNBT(t, `{Level:{
+		InhabitedTime:123L,
+		Biomes:[I;1,2,3,4],
+		Heightmap:[B;9,8,7],
+		Entities:[{id:"cow",Pos:[1.0d,2.0d,3.0d]},{id:"pig",Pos:[4.0d,5.0d,6.0d]}],
+		Sections:[{Y:0b,Name:"Ünïcødé"},{Y:1b},{Y:2b}],
+		Strings:["a","b"],
+		Longs:[1L,2L]
+	}}`)
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	e.SetSortKeys(true)
+	if network {
+		e.UseNetworkFormat()
+	}
+	if err := e.Encode("root", level); err != nil {
+		t.Fatalf("Could not encode: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodePaths(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"Level.InhabitedTime"}, []string{"Level.InhabitedTime = 123L"}},
+		{[]string{"Level.Missing"}, nil},
+		{[]string{"Level.Entities[].id"}, []string{`Level.Entities[0].id = "cow"`, `Level.Entities[1].id = "pig"`}},
+		{[]string{"Level.Entities[-1].Pos[0]"}, []string{"Level.Entities[1].Pos[0] = 4.0d"}},
+		{[]string{"Level.Entities[{id:\"pig\"}].Pos[2]"}, []string{"Level.Entities[1].Pos[2] = 6.0d"}},
+		{[]string{"Level.Biomes[2]", "Level.Heightmap"}, []string{"Level.Biomes[2] = 3", "Level.Heightmap = [B;9b,8b,7b]"}},
+		{[]string{"Level.Sections[{Y:1b}]"}, []string{"Level.Sections[1] = {Y:1b}"}},
+		{[]string{"Level.Sections[0].Name"}, []string{`Level.Sections[0].Name = "Ünïcødé"`}},
+		{[]string{"{Level:{InhabitedTime:123L}}.Level.Longs[1]"}, []string{"Level.Longs[1] = 2L"}},
+		{[]string{"{Level:{InhabitedTime:1L}}.Level.Longs[1]"}, nil},
+		{[]string{"Level.Strings[]", "Level.Strings[0]"}, []string{`Level.Strings[0] = "a"`, `Level.Strings[0] = "a"`, `Level.Strings[1] = "b"`}},
+	}
+
+	for _, network := range []bool{false, true} {
+		data := selectTestData(t, network)
+		for _, test := range tests {
+			var paths []Path
+			for _, p := range test.paths {
+				paths = append(paths, MustParsePath(p))
+			}
+
+			// Read the tag twice, to check the stream is at the right position afterwards.
+			d := NewDecoder(bytes.NewReader(append(append([]byte{}, data...), data...)))
+			if network {
+				d.UseNetworkFormat()
+			}
+			matches, name, err := d.DecodePaths(paths...)
+			if err != nil {
+				t.Fatalf("%v: %s", test.paths, err)
+			}
+			if name != "root" {
+				t.Errorf("%v: Wrong name %q", test.paths, name)
+			}
+
+			var have []string
+			for _, m := range matches {
+				have = append(have, m.Path.String()+" = "+FormatSNBT(m.Tag, SNBTOptions{}))
+			}
+			if len(have) != len(test.want) {
+				t.Errorf("%v: Want %q, have %q", test.paths, test.want, have)
+				continue
+			}
+			for i := range have {
+				if have[i] != test.want[i] {
+					t.Errorf("%v: Want %q, have %q", test.paths, test.want, have)
+					break
+				}
+			}
+
+			if _, _, err := d.Decode(); err != nil {
+				t.Errorf("%v: Could not read the following tag: %s", test.paths, err)
+			}
+		}
+	}
+}
+
+func TestDecodePathsErrors(t *testing.T) {
+	data := selectTestData(t, false)
+	d := NewDecoder(bytes.NewReader(data[:len(data)-10]))
+	_, _, err := d.DecodePaths(MustParsePath("Level.Longs"))
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Errorf("Want DecodeError, have %v", err)
+	}
+
+	d = NewDecoder(bytes.NewReader(data))
+	d.SetLimits(Limits{MaxDepth: 2})
+	if _, _, err := d.DecodePaths(MustParsePath("Level.InhabitedTime")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Skipped data should respect the depth limit, have %v", err)
+	}
+}
+
+func TestSkipDoesNotAllocate(t *testing.T) {
+	data := selectTestData(t, false)
+	r := bytes.NewReader(data)
+	d := newDecoder(r)
+	path := MustParsePath("Level.Missing")
+	d.DecodePaths(path)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(data)
+		d.t.src, d.t.r = r, r
+		d.DecodePaths(path)
+	})
+	// The root token (with its name) and the path stack allocate a little, the skipped data nothing.
+	if allocs > 5 {
+		t.Errorf("DecodePaths allocated %v times", allocs)
+	}
+}
+
+func TestLazyTag(t *testing.T) {
+	for _, network := range []bool{false, true} {
+		data := selectTestData(t, network)
+		d := NewDecoder(bytes.NewReader(append(append([]byte{}, data...), data...)))
+		if network {
+			d.UseNetworkFormat()
+		}
+		lazy, name, err := d.DecodeLazy()
+		if err != nil || name != "root" {
+			t.Fatalf("DecodeLazy: %q %v", name, err)
+		}
+
+		level, err := lazy.Entry("Level")
+		if err != nil {
+			t.Fatalf("Could not get Level: %s", err)
+		}
+		entities, _ := level.Entry("Entities")
+		pig, err := entities.Elem(1)
+		if err != nil {
+			t.Fatalf("Could not get entity 1: %s", err)
+		}
+		id, _ := pig.Entry("id")
+		if tag, err := id.Tag(); err != nil || tag.Payload != "pig" {
+			t.Errorf("Wrong id %s (err: %v)", tag, err)
+		}
+
+		if _, err := level.Entry("Missing"); err != NotFound {
+			t.Errorf("Want NotFound, have %v", err)
+		}
+		if _, err := entities.Elem(2); err != NotFound {
+			t.Errorf("Want NotFound, have %v", err)
+		}
+		if _, err := pig.Elem(0); err != WrongType {
+			t.Errorf("Want WrongType, have %v", err)
+		}
+
+		matches, err := level.DecodePaths(MustParsePath("Sections[].Y"))
+		if err != nil || len(matches) != 3 || matches[2].Tag.Payload != byte(2) {
+			t.Errorf("Wrong matches %v (err: %v)", matches, err)
+		}
+
+		whole, err := lazy.Tag()
+		full, _, _ := d.Decode()
+		if err != nil || !Equal(whole, full) {
+			t.Errorf("Decoded LazyTag differs: %v", err)
+		}
+	}
+}
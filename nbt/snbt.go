@@ -0,0 +1,496 @@
+package nbt
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SNBT ("stringified NBT") is the text representation of NBT used in Minecraft commands, e.g.
+//
+//	{Count:1b,id:"minecraft:stone",tag:{Damage:0s}}
+
+// SNBTSyntaxError is returned by ParseSNBT for malformed input.
+type SNBTSyntaxError struct {
+	Line, Col int // Position of the error, both starting at 1. Col counts characters, not bytes.
+	Msg       string
+}
+
+func (e *SNBTSyntaxError) Error() string {
+	return fmt.Sprintf("snbt: %d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+var (
+	snbtByteRe   = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)[bB]$`)
+	snbtShortRe  = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)[sS]$`)
+	snbtIntRe    = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)$`)
+	snbtLongRe   = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)[lL]$`)
+	snbtFloatRe  = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?[fF]$`)
+	snbtDoubleRe = regexp.MustCompile(`^[-+]?(?:[0-9]+\.?|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?[dD]$`)
+	snbtPlainRe  = regexp.MustCompile(`^[-+]?(?:(?:[0-9]+\.|[0-9]*\.[0-9]+)(?:[eE][-+]?[0-9]+)?|[0-9]+[eE][-+]?[0-9]+)$`)
+)
+
+func isUnquotedChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+type snbtParser struct {
+	s   string
+	pos int
+}
+
+// ParseSNBT parses a SNBT string into a Tag.
+//
+// Numbers without a type suffix are TAG_Int, or TAG_Double if they have a fractional part or an exponent. true and false are
+// TAG_Bytes with the value 1 and 0. Other unquoted words are TAG_Strings. Errors are returned as *SNBTSyntaxError.
+func ParseSNBT(s string) (Tag, error) {
+	p := &snbtParser{s: s}
+	tag, err := p.parseValue()
+	if err != nil {
+		return Tag{}, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return Tag{}, p.errorf("unexpected trailing data")
+	}
+	return tag, nil
+}
+
+func (p *snbtParser) errorf(format string, a ...interface{}) error {
+	line := 1 + strings.Count(p.s[:p.pos], "\n")
+	lineStart := strings.LastIndex(p.s[:p.pos], "\n") + 1
+	col := 1 + utf8.RuneCountInString(p.s[lineStart:p.pos])
+	return &SNBTSyntaxError{line, col, fmt.Sprintf(format, a...)}
+}
+
+func (p *snbtParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next non-space character or 0 at the end of the input.
+func (p *snbtParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *snbtParser) expect(c byte) error {
+	if p.peek() != c {
+		if p.pos >= len(p.s) {
+			return p.errorf("expected '%c', got end of input", c)
+		}
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *snbtParser) parseQuoted() (string, error) {
+	q := p.s[p.pos]
+	p.pos++
+
+	var sb strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch c {
+		case q:
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.s) {
+				break
+			}
+			if e := p.s[p.pos]; e == '\\' || e == '"' || e == '\'' {
+				sb.WriteByte(e)
+				p.pos++
+				continue
+			}
+			return "", p.errorf("invalid escape sequence")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *snbtParser) parseUnquoted() string {
+	start := p.pos
+	for p.pos < len(p.s) && isUnquotedChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *snbtParser) parseKey() (string, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.parseQuoted()
+	case c != 0 && isUnquotedChar(c):
+		return p.parseUnquoted(), nil
+	}
+	return "", p.errorf("expected key")
+}
+
+func (p *snbtParser) parseValue() (Tag, error) {
+	switch c := p.peek(); {
+	case c == '{':
+		return p.parseCompound()
+	case c == '[':
+		return p.parseListOrArray()
+	case c == '"' || c == '\'':
+		s, err := p.parseQuoted()
+		return NewStringTag(s), err
+	case c != 0 && isUnquotedChar(c):
+		start := p.pos
+		tag, err := parseSNBTWord(p.parseUnquoted())
+		if err != nil {
+			p.pos = start
+			return Tag{}, p.errorf("%s", err)
+		}
+		return tag, nil
+	case c == 0:
+		return Tag{}, p.errorf("expected value, got end of input")
+	}
+	return Tag{}, p.errorf("expected value")
+}
+
+// parseSNBTWord converts an unquoted word into a number, boolean or string tag.
+func parseSNBTWord(w string) (Tag, error) {
+	switch {
+	case snbtByteRe.MatchString(w):
+		v, err := strconv.ParseInt(w[:len(w)-1], 10, 8)
+		return NewByteTag(byte(v)), rangeError(err, w)
+	case snbtShortRe.MatchString(w):
+		v, err := strconv.ParseInt(w[:len(w)-1], 10, 16)
+		return NewShortTag(int16(v)), rangeError(err, w)
+	case snbtIntRe.MatchString(w):
+		v, err := strconv.ParseInt(w, 10, 32)
+		return NewIntTag(int32(v)), rangeError(err, w)
+	case snbtLongRe.MatchString(w):
+		v, err := strconv.ParseInt(w[:len(w)-1], 10, 64)
+		return NewLongTag(v), rangeError(err, w)
+	case snbtFloatRe.MatchString(w):
+		v, err := strconv.ParseFloat(w[:len(w)-1], 32)
+		return NewFloatTag(float32(v)), rangeError(err, w)
+	case snbtDoubleRe.MatchString(w):
+		v, err := strconv.ParseFloat(w[:len(w)-1], 64)
+		return NewDoubleTag(v), rangeError(err, w)
+	case snbtPlainRe.MatchString(w):
+		v, err := strconv.ParseFloat(w, 64)
+		return NewDoubleTag(v), rangeError(err, w)
+	case w == "true":
+		return NewByteTag(1), nil
+	case w == "false":
+		return NewByteTag(0), nil
+	}
+	return NewStringTag(w), nil
+}
+
+func rangeError(err error, w string) error {
+	if err != nil {
+		return fmt.Errorf("number out of range: %s", w)
+	}
+	return nil
+}
+
+func (p *snbtParser) parseCompound() (Tag, error) {
+	p.pos++ // {
+	comp := make(TagCompound)
+	if p.peek() == '}' {
+		p.pos++
+		return Tag{TAG_Compound, comp}, nil
+	}
+
+	for {
+		key, err := p.parseKey()
+		if err != nil {
+			return Tag{}, err
+		}
+		if err := p.expect(':'); err != nil {
+			return Tag{}, err
+		}
+		tag, err := p.parseValue()
+		if err != nil {
+			return Tag{}, err
+		}
+		comp[key] = tag
+
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect('}'); err != nil {
+			return Tag{}, err
+		}
+		return Tag{TAG_Compound, comp}, nil
+	}
+}
+
+func (p *snbtParser) parseListOrArray() (Tag, error) {
+	p.pos++ // [
+	p.skipSpace()
+	if p.pos+1 < len(p.s) && p.s[p.pos+1] == ';' {
+		switch p.s[p.pos] {
+		case 'B':
+			return p.parseArray(TAG_Byte_Array, TAG_Byte)
+		case 'I':
+			return p.parseArray(TAG_Int_Array, TAG_Int)
+		case 'L':
+			return p.parseArray(TAG_Long_Array, TAG_Long)
+		}
+		return Tag{}, p.errorf("invalid array type '%c'", p.s[p.pos])
+	}
+
+	l := TagList{Type: TAG_End, Elems: []interface{}{}}
+	if p.peek() == ']' {
+		p.pos++
+		return Tag{TAG_List, l}, nil
+	}
+
+	for {
+		p.skipSpace()
+		start := p.pos
+		tag, err := p.parseValue()
+		if err != nil {
+			return Tag{}, err
+		}
+		if len(l.Elems) == 0 {
+			l.Type = tag.Type
+		} else if tag.Type != l.Type {
+			p.pos = start
+			return Tag{}, p.errorf("can not insert %s into list of %s", tag.Type, l.Type)
+		}
+		l.Elems = append(l.Elems, tag.Payload)
+
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect(']'); err != nil {
+			return Tag{}, err
+		}
+		return Tag{TAG_List, l}, nil
+	}
+}
+
+func (p *snbtParser) parseArray(at, et TagType) (Tag, error) {
+	p.pos += 2 // Type and ;
+
+	var bs []byte
+	var is []int32
+	var ls []int64
+
+	if p.peek() != ']' {
+		for {
+			p.skipSpace()
+			start := p.pos
+			if c := p.peek(); c == 0 || !isUnquotedChar(c) {
+				return Tag{}, p.errorf("expected %s", et)
+			}
+			w := p.parseUnquoted()
+			tag, err := parseSNBTWord(w)
+			if err == nil && tag.Type == TAG_Int && et != TAG_Int {
+				// Numbers without suffix are allowed in all arrays, if they fit.
+				v := tag.Payload.(int32)
+				switch {
+				case et == TAG_Long:
+					tag = NewLongTag(int64(v))
+				case v >= math.MinInt8 && v <= math.MaxInt8:
+					tag = NewByteTag(byte(v))
+				}
+			}
+			if err != nil || tag.Type != et {
+				p.pos = start
+				return Tag{}, p.errorf("expected %s, got %q", et, w)
+			}
+
+			switch et {
+			case TAG_Byte:
+				bs = append(bs, tag.Payload.(byte))
+			case TAG_Int:
+				is = append(is, tag.Payload.(int32))
+			case TAG_Long:
+				ls = append(ls, tag.Payload.(int64))
+			}
+
+			if p.peek() == ',' {
+				p.pos++
+				continue
+			}
+			break
+		}
+	}
+	if err := p.expect(']'); err != nil {
+		return Tag{}, err
+	}
+
+	switch at {
+	case TAG_Byte_Array:
+		return NewByteArrayTag(append([]byte{}, bs...)), nil
+	case TAG_Int_Array:
+		return NewIntArrayTag(append([]int32{}, is...)), nil
+	}
+	return NewLongArrayTag(append([]int64{}, ls...)), nil
+}
+
+// SNBTOptions control the output of FormatSNBT.
+type SNBTOptions struct {
+	// Indent is used to indent nested compounds and lists. If empty, the output is compact and on a single line.
+	Indent string
+}
+
+// FormatSNBT formats a Tag as SNBT. Compound keys are sorted, so the output is deterministic.
+//
+// NaN and infinite floats can not be represented in SNBT and will not be read back as numbers by ParseSNBT.
+func FormatSNBT(tag Tag, opts SNBTOptions) string {
+	var sb strings.Builder
+	formatSNBT(&sb, tag, opts, 0)
+	return sb.String()
+}
+
+func quoteSNBT(s string) string {
+	q := byte('"')
+	if strings.Contains(s, `"`) && !strings.Contains(s, "'") {
+		q = '\''
+	}
+
+	var sb strings.Builder
+	sb.WriteByte(q)
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == q || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte(q)
+	return sb.String()
+}
+
+func formatSNBTKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isUnquotedChar(key[i]) {
+			return quoteSNBT(key)
+		}
+	}
+	return key
+}
+
+func formatSNBTFloat(v float64, bits int) string {
+	s := strconv.FormatFloat(v, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func snbtNewline(sb *strings.Builder, opts SNBTOptions, depth int) {
+	if opts.Indent == "" {
+		return
+	}
+	sb.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		sb.WriteString(opts.Indent)
+	}
+}
+
+func formatSNBTArray(sb *strings.Builder, prefix string, n int, elem func(i int) string, opts SNBTOptions) {
+	sep := ","
+	sb.WriteString("[" + prefix + ";")
+	if opts.Indent != "" {
+		sep = ", "
+		if n > 0 {
+			sb.WriteByte(' ')
+		}
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(elem(i))
+	}
+	sb.WriteByte(']')
+}
+
+func formatSNBT(sb *strings.Builder, tag Tag, opts SNBTOptions, depth int) {
+	switch tag.Type {
+	case TAG_Byte:
+		sb.WriteString(strconv.Itoa(int(int8(tag.Payload.(byte)))) + "b")
+	case TAG_Short:
+		sb.WriteString(strconv.Itoa(int(tag.Payload.(int16))) + "s")
+	case TAG_Int:
+		sb.WriteString(strconv.Itoa(int(tag.Payload.(int32))))
+	case TAG_Long:
+		sb.WriteString(strconv.FormatInt(tag.Payload.(int64), 10) + "L")
+	case TAG_Float:
+		sb.WriteString(formatSNBTFloat(float64(tag.Payload.(float32)), 32) + "f")
+	case TAG_Double:
+		sb.WriteString(formatSNBTFloat(tag.Payload.(float64), 64) + "d")
+	case TAG_String:
+		sb.WriteString(quoteSNBT(tag.Payload.(string)))
+	case TAG_Byte_Array:
+		a := tag.Payload.([]byte)
+		formatSNBTArray(sb, "B", len(a), func(i int) string { return strconv.Itoa(int(int8(a[i]))) + "b" }, opts)
+	case TAG_Int_Array:
+		a := tag.Payload.([]int32)
+		formatSNBTArray(sb, "I", len(a), func(i int) string { return strconv.Itoa(int(a[i])) }, opts)
+	case TAG_Long_Array:
+		a := tag.Payload.([]int64)
+		formatSNBTArray(sb, "L", len(a), func(i int) string { return strconv.FormatInt(a[i], 10) + "L" }, opts)
+	case TAG_List:
+		l := tag.Payload.(TagList)
+		sb.WriteByte('[')
+		for i, elem := range l.Elems {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			snbtNewline(sb, opts, depth+1)
+			formatSNBT(sb, Tag{l.Type, elem}, opts, depth+1)
+		}
+		if len(l.Elems) > 0 {
+			snbtNewline(sb, opts, depth)
+		}
+		sb.WriteByte(']')
+	case TAG_Compound:
+		comp := tag.Payload.(TagCompound)
+		keys := make([]string, 0, len(comp))
+		for k := range comp {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			snbtNewline(sb, opts, depth+1)
+			sb.WriteString(formatSNBTKey(k) + ":")
+			if opts.Indent != "" {
+				sb.WriteByte(' ')
+			}
+			formatSNBT(sb, comp[k], opts, depth+1)
+		}
+		if len(keys) > 0 {
+			snbtNewline(sb, opts, depth)
+		}
+		sb.WriteByte('}')
+	}
+}
@@ -0,0 +1,111 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSNBT(t *testing.T) {
+	tag, err := ParseSNBT(`{Count:1b,id:"minecraft:stone",tag:{Damage:0s}, "quoted key" : 'it\'s',
+		ints: [1, -2, +3], longs: [L; 1L, -2], bytes: [B;1b,-2B,3], is:[I;], big: 9000000000L,
+		f: 0.5f, d: .25, e: 1e3d, exp: 1e5, nexp: -2E-1, flag: true, word: minecraft.stone, nested: [[], [{}]]}`)
+	if err != nil {
+		t.Fatalf("Could not parse SNBT: %s", err)
+	}
+
+	want := Tag{TAG_Compound, TagCompound{
+		"Count":      NewByteTag(1),
+		"id":         NewStringTag("minecraft:stone"),
+		"tag":        Tag{TAG_Compound, TagCompound{"Damage": NewShortTag(0)}},
+		"quoted key": NewStringTag("it's"),
+		"ints":       NewListTag(TAG_Int, []int32{1, -2, 3}),
+		"longs":      NewLongArrayTag([]int64{1, -2}),
+		"bytes":      NewByteArrayTag([]byte{1, 0xfe, 3}),
+		"is":         NewIntArrayTag([]int32{}),
+		"big":        NewLongTag(9000000000),
+		"f":          NewFloatTag(0.5),
+		"d":          NewDoubleTag(0.25),
+		"e":          NewDoubleTag(1000),
+		"exp":        NewDoubleTag(1e5),
+		"nexp":       NewDoubleTag(-0.2),
+		"flag":       NewByteTag(1),
+		"word":       NewStringTag("minecraft.stone"),
+		"nested": NewListTag(TAG_List, []TagList{
+			{TAG_End, []interface{}{}},
+			{TAG_Compound, []interface{}{TagCompound{}}},
+		}),
+	}}
+	if !reflect.DeepEqual(tag, want) {
+		t.Errorf("Wrong result.\nWant: %s\nHave: %s", want, tag)
+	}
+}
+
+func TestSNBTRoundtrip(t *testing.T) {
+	const compact = `{Count:-1b,"a b":[I;1,2],d:0.4931287132182315d,empty:[],f:1.0f,id:'say "hi"',l:[L;],list:[{x:1s},{}],long:-5L,q:'"',s:"it's \\"}`
+	const pretty = `{
+  Count: -1b,
+  "a b": [I; 1, 2],
+  d: 0.4931287132182315d,
+  empty: [],
+  f: 1.0f,
+  id: 'say "hi"',
+  l: [L;],
+  list: [
+    {
+      x: 1s
+    },
+    {}
+  ],
+  long: -5L,
+  q: '"',
+  s: "it's \\"
+}`
+
+	tag, err := ParseSNBT(compact)
+	if err != nil {
+		t.Fatalf("Could not parse SNBT: %s", err)
+	}
+
+	if s := FormatSNBT(tag, SNBTOptions{}); s != compact {
+		t.Errorf("Wrong compact output.\nWant: %s\nHave: %s", compact, s)
+	}
+	if s := FormatSNBT(tag, SNBTOptions{Indent: "  "}); s != pretty {
+		t.Errorf("Wrong pretty output.\nWant: %s\nHave: %s", pretty, s)
+	}
+
+	tag2, err := ParseSNBT(pretty)
+	if err != nil {
+		t.Fatalf("Could not parse pretty SNBT: %s", err)
+	}
+	if !reflect.DeepEqual(tag, tag2) {
+		t.Errorf("Compact and pretty SNBT differ.\nCompact: %s\nPretty: %s", tag, tag2)
+	}
+}
+
+func TestParseSNBTErrors(t *testing.T) {
+	tests := []struct {
+		in        string
+		line, col int
+	}{
+		{`{a:1`, 1, 5},
+		{`{a:1,}`, 1, 6},
+		{"{\n  a: [1, 2b]\n}", 2, 10},
+		{"{\n  a: \"x\",\n  \"ä\": [B; 1, 300]\n}", 3, 15},
+		{`[X;1]`, 1, 2},
+		{`"unterminated`, 1, 14},
+		{`{a:1} x`, 1, 7},
+		{`{a:300b}`, 1, 4},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSNBT(test.in)
+		e, ok := err.(*SNBTSyntaxError)
+		if !ok {
+			t.Errorf("%#v: Want *SNBTSyntaxError, have %#v", test.in, err)
+			continue
+		}
+		if e.Line != test.line || e.Col != test.col {
+			t.Errorf("%#v: Want error at %d:%d, have %s", test.in, test.line, test.col, e)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreamMultipleRootTags(t *testing.T) {
+	for _, c := range []Compression{NoCompression, Gzip, Zlib} {
+		for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+			buf := new(bytes.Buffer)
+			e := NewEncoder(buf)
+			e.SetCompression(c)
+			e.SetByteOrder(order)
+			for i := int32(0); i < 3; i++ {
+				comp := TagCompound{"i": NewIntTag(i), "d": NewDoubleTag(float64(i) / 2)}
+				if err := e.Encode("tag", Tag{TAG_Compound, comp}); err != nil {
+					t.Fatalf("%s/%s: Could not encode: %s", c, order, err)
+				}
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("%s/%s: Could not close encoder: %s", c, order, err)
+			}
+
+			d := NewDecoder(buf)
+			d.SetCompression(c)
+			d.SetByteOrder(order)
+			for i := int32(0); i < 3; i++ {
+				tag, name, err := d.Decode()
+				if err != nil {
+					t.Fatalf("%s/%s: Could not decode tag %d: %s", c, order, i, err)
+				}
+				if name != "tag" {
+					t.Errorf("%s/%s: Wrong name %#v", c, order, name)
+				}
+				comp := tag.Payload.(TagCompound)
+				if tag, ok := getKey(t, comp, "i", TAG_Int); ok {
+					tagPayloadCompare(t, "i", tag, i)
+				}
+				if tag, ok := getKey(t, comp, "d", TAG_Double); ok {
+					tagPayloadCompare(t, "d", tag, float64(i)/2)
+				}
+			}
+			if _, _, err := d.Decode(); err != io.EOF {
+				t.Errorf("%s/%s: Want io.EOF after last tag, have %v", c, order, err)
+			}
+		}
+	}
+}
+
+func TestReadNamedTagDoesNotOverread(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "a", NewShortTag(1))
+	WriteNamedTag(buf, "b", NewShortTag(2))
+
+	for _, want := range []string{"a", "b"} {
+		if _, name, err := ReadNamedTag(buf); err != nil || name != want {
+			t.Errorf("Want tag %#v, have %#v (err: %v)", want, name, err)
+		}
+	}
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "Level", Tag{TAG_Compound, TagCompound{"x": NewLongTag(1)}})
+	data := buf.Bytes()
+
+	if _, _, err := NewDecoder(bytes.NewReader(data[:len(data)-3])).Decode(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Want io.ErrUnexpectedEOF, have %v", err)
+	}
+}
+
+func TestDecoderMaxBytes(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "data", NewByteArrayTag(make([]byte, 100)))
+	data := buf.Bytes()
+
+	d := NewDecoder(bytes.NewReader(data))
+	d.SetLimits(Limits{MaxBytes: int64(len(data))})
+	if _, _, err := d.Decode(); err != nil {
+		t.Errorf("Tag with exactly MaxBytes should be accepted, have %v", err)
+	}
+
+	d = NewDecoder(bytes.NewReader(data))
+	d.SetLimits(Limits{MaxBytes: int64(len(data) - 1)})
+	if _, _, err := d.Decode(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Want ErrLimitExceeded, have %v", err)
+	}
+}
+
+func TestNamelessRoot(t *testing.T) {
+	tag := Tag{TAG_Compound, TagCompound{"text": NewStringTag("hi")}}
+	want := []byte{TAG_Compound, TAG_String, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x02, 'h', 'i', TAG_End}
+
+	buf := new(bytes.Buffer)
+	if err := WriteNamelessTag(buf, tag); err != nil {
+		t.Fatalf("Could not write tag: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Wrong encoding.\nWant: % x\nHave: % x", want, buf.Bytes())
+	}
+
+	// An empty slot followed by our tag
+	buf = bytes.NewBuffer(append([]byte{TAG_End}, want...))
+	empty, err := ReadNamelessTag(buf)
+	if err != nil || empty.Type != TAG_End {
+		t.Errorf("Want empty TAG_End tag, have %s (err: %v)", empty, err)
+	}
+	have, err := ReadNamelessTag(buf)
+	if err != nil {
+		t.Fatalf("Could not read tag: %s", err)
+	}
+	if s, err := have.Payload.(TagCompound).GetString("text"); err != nil || s != "hi" {
+		t.Errorf("Wrong text %#v (err: %v)", s, err)
+	}
+}
+
+func TestPayload(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WritePayload(buf, NewListTag(TAG_Short, []int16{1, 2})); err != nil {
+		t.Fatalf("Could not write payload: %s", err)
+	}
+	want := []byte{TAG_Short, 0x00, 0x00, 0x00, 0x02, 0x00, 0x01, 0x00, 0x02}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Wrong encoding.\nWant: % x\nHave: % x", want, buf.Bytes())
+	}
+
+	tag, err := ReadPayload(buf, TAG_List)
+	if err != nil {
+		t.Fatalf("Could not read payload: %s", err)
+	}
+	if l := tag.Payload.(TagList); tag.Type != TAG_List || l.Type != TAG_Short || len(l.Elems) != 2 {
+		t.Errorf("Wrong payload %s", tag)
+	}
+}
+
+func TestDecoderHugeDeclaredLength(t *testing.T) {
+	inputs := map[string][]byte{
+		"Int array":  {TAG_Int_Array, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff},
+		"Long array": {TAG_Long_Array, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff},
+		"Byte array": {TAG_Byte_Array, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff},
+		"List":       {TAG_List, 0x00, 0x00, TAG_Int, 0x7f, 0xff, 0xff, 0xff},
+	}
+	for what, data := range inputs {
+		if _, _, err := ReadNamedTag(bytes.NewReader(data)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: Want io.ErrUnexpectedEOF, have %v", what, err)
+		}
+	}
+}
+
+func TestDecoderEndListWithElements(t *testing.T) {
+	// A list of TAG_End declaring 2^31-1 elements, which would take no space.
+	data := []byte{TAG_List, 0x00, 0x00, TAG_End, 0x7f, 0xff, 0xff, 0xff}
+
+	var de *DecodeError
+	if _, _, err := ParseNamedTag(data); !errors.As(err, &de) || de.Type != TAG_List {
+		t.Errorf("ParseNamedTag: Want DecodeError, have %v", err)
+	}
+	if _, _, err := ReadNamedTag(bytes.NewReader(data)); !errors.As(err, &de) || de.Type != TAG_List {
+		t.Errorf("ReadNamedTag: Want DecodeError, have %v", err)
+	}
+	if _, _, err := NewBytesDecoder(data).DecodePaths(MustParsePath("x")); err == nil {
+		t.Errorf("DecodePaths: Want error")
+	}
+	if _, _, err := NewBytesDecoder(data).DecodeLazy(); err == nil {
+		t.Errorf("DecodeLazy: Want error")
+	}
+
+	lazy, _, err := NewBytesDecoder([]byte{TAG_List, 0x00, 0x00, TAG_Byte, 0x00, 0x00, 0x00, 0x01, 0x05}).DecodeLazy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lazy.raw[0] = TAG_End
+	if _, err := lazy.Elem(0); err == nil || err == NotFound {
+		t.Errorf("LazyTag.Elem: Want error, have %v", err)
+	}
+
+	if _, _, err := ParseNamedTag([]byte{TAG_List, 0x00, 0x00, TAG_End, 0x00, 0x00, 0x00, 0x00}); err != nil {
+		t.Errorf("Empty list of TAG_End should be accepted, have %v", err)
+	}
+}
+
+func TestDecoderLimitErrors(t *testing.T) {
+	nested := []byte{TAG_Compound, 0x00, 0x00}
+	for i := 0; i < 10; i++ {
+		nested = append(nested, TAG_Compound, 0x00, 0x00)
+	}
+
+	tests := []struct {
+		limits Limits
+		data   []byte
+		limit  string
+	}{
+		{Limits{MaxDepth: 5}, nested, "MaxDepth"},
+		{Limits{MaxElems: 3}, []byte{TAG_Int_Array, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04}, "MaxElems"},
+		{Limits{MaxElems: 3}, []byte{TAG_List, 0x00, 0x00, TAG_Byte, 0x00, 0x00, 0x00, 0x04}, "MaxElems"},
+		{Limits{MaxStringLen: 3}, []byte{TAG_Byte, 0x00, 0x04, 'n', 'a', 'm', 'e', 0x01}, "MaxStringLen"},
+		{Limits{MaxBytes: 5}, []byte{TAG_Long, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 1}, "MaxBytes"},
+	}
+	for _, test := range tests {
+		d := NewDecoder(bytes.NewReader(test.data))
+		d.SetLimits(test.limits)
+		_, _, err := d.Decode()
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: Want ErrLimitExceeded, have %v", test.limit, err)
+			continue
+		}
+		var le *LimitError
+		if !errors.As(err, &le) || le.Limit != test.limit {
+			t.Errorf("%s: Wrong LimitError %v", test.limit, err)
+		}
+	}
+}
+
+func TestDecoderDefaultMaxDepth(t *testing.T) {
+	nestedLists := func(depth int) []byte {
+		data := []byte{TAG_List, 0x00, 0x00}
+		for i := 1; i < depth; i++ {
+			data = append(data, TAG_List, 0x00, 0x00, 0x00, 0x01)
+		}
+		return append(data, TAG_End, 0x00, 0x00, 0x00, 0x00)
+	}
+
+	if _, _, err := ParseNamedTag(nestedLists(DefaultMaxDepth)); err != nil {
+		t.Errorf("%d nested lists should be accepted, have %v", DefaultMaxDepth, err)
+	}
+
+	// Without limits, deep nesting must fail instead of exhausting the stack.
+	deep := nestedLists(1 << 20)
+	decoders := map[string]func() error{
+		"ReadNamedTag": func() error { _, _, err := ReadNamedTag(bytes.NewReader(deep)); return err },
+		"Decode":       func() error { _, _, err := NewBytesDecoder(deep).Decode(); return err },
+		"DecodeLazy":   func() error { _, _, err := NewBytesDecoder(deep).DecodeLazy(); return err },
+		"DecodePaths": func() error {
+			_, _, err := NewBytesDecoder(deep).DecodePaths(MustParsePath("[]"))
+			return err
+		},
+	}
+	for what, decode := range decoders {
+		var le *LimitError
+		if err := decode(); !errors.As(err, &le) || le.Limit != "MaxDepth" || le.Max != DefaultMaxDepth {
+			t.Errorf("%s: Want MaxDepth LimitError, have %v", what, err)
+		}
+	}
+}
+
+func TestDecoderLargeByteArray(t *testing.T) {
+	data := make([]byte, 3*allocChunk+17)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "data", NewByteArrayTag(data))
+
+	tag, _, err := ReadNamedTag(buf)
+	if err != nil {
+		t.Fatalf("Could not read tag: %s", err)
+	}
+	if !bytes.Equal(tag.Payload.([]byte), data) {
+		t.Errorf("Byte array changed")
+	}
+}
+
+func TestDecodeErrorPosition(t *testing.T) {
+	sections := []interface{}{TagCompound{}, TagCompound{}, TagCompound{}, TagCompound{"BlockStates": NewLongArrayTag([]int64{1, 2})}}
+	level := TagCompound{"Sections": Tag{TAG_List, TagList{TAG_Compound, sections}}}
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "", Tag{TAG_Compound, TagCompound{"Level": Tag{TAG_Compound, level}}})
+	data := buf.Bytes()
+
+	// Cut off in the middle of the second long.
+	_, _, err := ReadNamedTag(bytes.NewReader(data[:len(data)-8]))
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("Want *DecodeError, have %v", err)
+	}
+	if de.Path != "Level.Sections[3].BlockStates" || de.Type != TAG_Long_Array || de.Offset != int64(len(data)-8) {
+		t.Errorf("Wrong DecodeError %#v", de)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Cause should be io.ErrUnexpectedEOF, have %v", de.Err)
+	}
+
+	_, _, err = ReadNamedTag(bytes.NewReader([]byte{TAG_Compound, 0x00, 0x00, 0x2a, 0x00, 0x01, 'x'}))
+	if !errors.As(err, &de) || de.Path != "x" || de.Type != 0x2a || de.Offset != 7 {
+		t.Errorf("Unknown tag type: wrong error %v", err)
+	}
+
+	_, _, err = ReadGzipdNamedTag(bytes.NewReader([]byte("not gzip")))
+	if !errors.As(err, &de) || de.Type != TAG_End || de.Offset != 0 {
+		t.Errorf("Invalid gzip header: wrong error %v", err)
+	}
+}
+
+func TestReadAnyNamedTag(t *testing.T) {
+	orig, _, err := ReadGzipdNamedTag(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not read bigtest: %s", err)
+	}
+
+	for _, c := range []Compression{NoCompression, Gzip, Zlib} {
+		for _, tag := range []Tag{orig, NewStringTag("a string root"), NewIntTag(1)} {
+			buf := new(bytes.Buffer)
+			if err := WriteCompressedNamedTag(buf, c, "Level", tag); err != nil {
+				t.Fatalf("%s: Could not write: %s", c, err)
+			}
+			data := buf.Bytes()
+
+			have, name, hc, err := ReadAnyNamedTag(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s: Could not read: %s", c, err)
+			}
+			if hc != c {
+				t.Errorf("%s: Detected %s", c, hc)
+			}
+			if name != "Level" || !Equal(have, tag) {
+				t.Errorf("%s: Read wrong tag %#v %s", c, name, have)
+			}
+
+			buf.Reset()
+			if err := WriteCompressedNamedTag(buf, hc, name, have); err != nil {
+				t.Fatalf("%s: Could not write back: %s", c, err)
+			}
+			if _, _, hc, err := ReadAnyNamedTag(buf); err != nil || hc != c {
+				t.Errorf("%s: Written back with %s (%v)", c, hc, err)
+			}
+		}
+	}
+
+	if _, _, _, err := ReadAnyNamedTag(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("Empty input: want io.EOF, have %v", err)
+	}
+}
+
+func TestCompressions(t *testing.T) {
+	orig, _, err := ReadGzipdNamedTag(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not read bigtest: %s", err)
+	}
+
+	for _, c := range []Compression{NoCompression, Gzip, Zlib, LZ4, GzipLevel(1), ZlibLevel(9)} {
+		if have, ok := CompressionByID(c.ID()); !ok || have.ID() != c.ID() {
+			t.Errorf("%s: CompressionByID(%d) returned %v", c, c.ID(), have)
+		}
+
+		buf := new(bytes.Buffer)
+		if err := WriteCompressedNamedTag(buf, c, "Level", orig); err != nil {
+			t.Fatalf("%s: Could not write: %s", c, err)
+		}
+		tag, _, hc, err := ReadAnyNamedTag(buf)
+		if err != nil {
+			t.Fatalf("%s: Could not read: %s", c, err)
+		}
+		if hc.ID() != c.ID() || !Equal(tag, orig) {
+			t.Errorf("%s: Round trip failed, detected %s", c, hc)
+		}
+	}
+
+	if _, ok := CompressionByID(42); ok {
+		t.Errorf("Unknown id 42 should not be found")
+	}
+}
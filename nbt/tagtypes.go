@@ -14,6 +14,7 @@ const (
 	TAG_List
 	TAG_Compound
 	TAG_Int_Array
+	TAG_Long_Array
 )
 
 // TagType describes the type of a NBT tag. Valid values are the TAG_* constants.
@@ -45,6 +46,8 @@ func (tt TagType) String() string {
 		return "TAG_Compound"
 	case TAG_Int_Array:
 		return "TAG_Int_Array"
+	case TAG_Long_Array:
+		return "TAG_Long_Array"
 	default:
 		return "TAG_Unknown"
 	}
@@ -0,0 +1,1060 @@
+package nbt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+// Valid TokenKind values.
+const (
+	TokenCompoundStart TokenKind = iota + 1 // Start of a compound. Its entries follow up to a TokenCompoundEnd.
+	TokenCompoundEnd                        // End of a compound.
+	TokenListStart                          // Start of a list with ElemType and Len. Its elements follow, then a TokenListEnd.
+	TokenListEnd                            // End of a list.
+	TokenArrayStart                         // Header of an array with Len. Read the elements with ReadBytes, ReadInts or ReadLongs.
+	TokenValue                              // A tag of any other type with its Value.
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenCompoundStart:
+		return "compound start"
+	case TokenCompoundEnd:
+		return "compound end"
+	case TokenListStart:
+		return "list start"
+	case TokenListEnd:
+		return "list end"
+	case TokenArrayStart:
+		return "array start"
+	case TokenValue:
+		return "value"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is an event returned by Tokenizer.Next.
+type Token struct {
+	Kind     TokenKind
+	Type     TagType     // Type of the tag. For end tokens, the type of the ended tag.
+	Name     string      // Name of a compound entry or a named root tag. Empty for list elements and end tokens.
+	ElemType TagType     // Element type of a list (TokenListStart).
+	Len      int         // Number of elements of a list or array (TokenListStart, TokenArrayStart).
+	Value    interface{} // Payload of a TokenValue, with the same types as Tag.Payload.
+}
+
+// tokFrame is an open compound or list of a Tokenizer.
+type tokFrame struct {
+	list     bool
+	elemType TagType // Element type of a list.
+	left     int     // Remaining elements of a list.
+	index    int     // Index of the next element of a list.
+	base     int     // Length of the path outside of this tag.
+}
+
+// Tokenizer reads NBT data from an input stream one token at a time, without building Tags. Memory use does not
+// depend on the size of the data, except for strings, so large data can be searched or streamed through.
+//
+// A root tag of type TAG_Compound yields a TokenCompoundStart (with the name of the root tag), a token for each entry
+// (lists and compounds yield their start, their content and their end) and a TokenCompoundEnd. Other root tags yield
+// their tokens likewise. After a root tag, the next root tag of the stream follows.
+//
+// It is configured like a Decoder, which is built on a Tokenizer.
+type Tokenizer struct {
+	src      io.Reader
+	r        io.Reader
+	buffered bool
+	started  bool
+
+	format
+	nameless bool
+	comp     Compression
+
+	n        int64 // Bytes read of the current root tag.
+	off      int64 // Bytes read in total.
+	stack    []tokFrame
+	path     Path
+	pathKeep int     // Length of path to keep at the next token.
+	array    TagType // Type of the current array, if the last token was a TokenArrayStart.
+	arrayLen int     // Unread elements of the current array.
+	buf      [8]byte
+	scratch  []byte // Buffer for names and skipped data.
+
+	recording bool // Append all data read to rec (see DecodeLazy).
+	rec       []byte
+
+	fromBytes bool   // Read from in instead of r (see NewBytesDecoder).
+	in        []byte // The unread input.
+	alias     bool   // Return byte arrays and strings sharing memory with in.
+}
+
+// format is the part of the configuration of a Tokenizer that determines, how data is read.
+type format struct {
+	order   binary.ByteOrder
+	network bool
+	strings StringEncoding
+	limits  Limits
+}
+
+// NewTokenizer returns a new Tokenizer that reads from r. By default it reads uncompressed, big-endian data (as used by Java Edition).
+//
+// The Tokenizer buffers its input and may read more data than necessary from r.
+func NewTokenizer(r io.Reader) *Tokenizer {
+	t := newTokenizer(r)
+	t.buffered = true
+	return t
+}
+
+// NewBytesTokenizer returns a new Tokenizer that reads from data.
+func NewBytesTokenizer(data []byte) *Tokenizer {
+	t := new(Tokenizer)
+	t.initBytes(data)
+	return t
+}
+
+func newTokenizer(r io.Reader) *Tokenizer {
+	t := new(Tokenizer)
+	t.init(r)
+	return t
+}
+
+func (t *Tokenizer) init(r io.Reader) {
+	t.src = r
+	t.order = binary.BigEndian
+}
+
+func (t *Tokenizer) initBytes(data []byte) {
+	t.fromBytes, t.in = true, data
+	t.order = binary.BigEndian
+}
+
+// InputOffset returns the number of bytes of the input that have been consumed. With compression it counts the
+// uncompressed data. For a Tokenizer created with NewBytesTokenizer, this is the offset of the unread data.
+func (t *Tokenizer) InputOffset() int64 { return t.off }
+
+// SetByteOrder sets the byte order of numbers. See Decoder.SetByteOrder.
+func (t *Tokenizer) SetByteOrder(order binary.ByteOrder) { t.order = order }
+
+// UseNetworkFormat makes the Tokenizer read the network NBT format of Bedrock Edition. See Decoder.UseNetworkFormat.
+func (t *Tokenizer) UseNetworkFormat() {
+	t.network = true
+	t.order = binary.LittleEndian
+	t.strings = UTF8
+}
+
+// SetStringEncoding sets the encoding of strings. The default is ModifiedUTF8.
+func (t *Tokenizer) SetStringEncoding(enc StringEncoding) { t.strings = enc }
+
+// UseNamelessRoot makes the Tokenizer read root tags without a name. See Decoder.UseNamelessRoot.
+func (t *Tokenizer) UseNamelessRoot() { t.nameless = true }
+
+// SetLimits sets the limits for the data. MaxDepth limits the number of open compounds and lists (DefaultMaxDepth, if zero).
+func (t *Tokenizer) SetLimits(l Limits) { t.limits = l }
+
+// SetCompression sets the compression of the input stream. It must be called before the first call to Next.
+func (t *Tokenizer) SetCompression(c Compression) { t.comp = c }
+
+func (t *Tokenizer) start() error {
+	t.started = true
+	if t.fromBytes {
+		if isUncompressed(t.comp) {
+			return nil
+		}
+		t.src, t.fromBytes = bytes.NewReader(t.in), false
+	}
+
+	r := t.src
+	if !isUncompressed(t.comp) {
+		zr, err := t.comp.NewReader(r)
+		if err != nil {
+			return err
+		}
+		r = zr
+	}
+
+	if t.buffered {
+		r = bufio.NewReader(r)
+	}
+	t.r = r
+	return nil
+}
+
+// Path returns the path of the tag of the last token, relative to the root tag. It is only valid until the next call of Next.
+func (t *Tokenizer) Path() Path { return t.path }
+
+// Depth returns the number of open compounds and lists.
+func (t *Tokenizer) Depth() int { return len(t.stack) }
+
+// Next returns the next token.
+//
+// If the stream ends before the next root tag, Next returns io.EOF. Other errors are returned as *DecodeError; if the
+// stream ends within a tag, its cause is io.ErrUnexpectedEOF. After an error, the position in the stream is undefined
+// and Next starts reading a new root tag.
+//
+// Unread elements of an array are skipped.
+func (t *Tokenizer) Next() (Token, error) {
+	tok, err := t.next()
+	if err != nil {
+		t.abort()
+	}
+	return tok, err
+}
+
+// abort resets the state after an error, so the next token starts a root tag.
+func (t *Tokenizer) abort() {
+	t.stack = t.stack[:0]
+	t.array, t.arrayLen = TAG_End, 0
+	t.recording = false
+}
+
+func (t *Tokenizer) next() (Token, error) {
+	if err := t.skipArray(); err != nil {
+		return Token{}, err
+	}
+	t.path = t.path[:t.pathKeep]
+
+	if len(t.stack) == 0 {
+		if err := t.prepare(); err != nil {
+			return Token{}, t.fail(TAG_End, err)
+		}
+
+		var tt TagType
+		var name string
+		var err error
+		if t.nameless {
+			var _tt byte
+			_tt, err = t.readByte()
+			tt = TagType(_tt)
+		} else {
+			tt, name, err = t.readTagHeader()
+		}
+		if err != nil {
+			return Token{}, t.fail(tt, err)
+		}
+		return t.element(tt, name, 0)
+	}
+
+	f := &t.stack[len(t.stack)-1]
+	if f.list {
+		if f.left == 0 {
+			return t.end(), nil
+		}
+		f.left--
+		f.index++
+		base := len(t.path)
+		t.path = append(t.path, PathElem{Kind: PathIndex, Index: f.index - 1})
+		return t.element(f.elemType, "", base)
+	}
+
+	tt, name, err := t.readTagHeader()
+	if err != nil {
+		return Token{}, t.fail(TAG_Compound, err)
+	}
+	if tt == TAG_End {
+		return t.end(), nil
+	}
+	base := len(t.path)
+	t.path = append(t.path, PathElem{Name: name})
+	return t.element(tt, name, base)
+}
+
+// NextPayload is like Next, but reads a root tag of type tt without type and name (see Decoder.DecodePayload).
+// It must only be called before a root tag.
+func (t *Tokenizer) NextPayload(tt TagType) (Token, error) {
+	if len(t.stack) > 0 {
+		return Token{}, errors.New("nbt: NextPayload called within a root tag")
+	}
+	if err := t.skipArray(); err != nil {
+		return Token{}, err
+	}
+	if err := t.prepare(); err != nil {
+		return Token{}, t.fail(TAG_End, err)
+	}
+
+	tok, err := t.element(tt, "", 0)
+	if err != nil {
+		t.abort()
+	}
+	return tok, err
+}
+
+// prepare is called before reading a root tag.
+func (t *Tokenizer) prepare() error {
+	if !t.started {
+		if err := t.start(); err != nil {
+			return err
+		}
+	}
+
+	t.n = 0
+	t.path = t.path[:0]
+	t.pathKeep = 0
+	return nil
+}
+
+// end closes the innermost compound or list.
+func (t *Tokenizer) end() Token {
+	f := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	t.pathKeep = f.base
+	if f.list {
+		return Token{Kind: TokenListEnd, Type: TAG_List}
+	}
+	return Token{Kind: TokenCompoundEnd, Type: TAG_Compound}
+}
+
+// element reads the payload of a tag of type tt (or the header of its payload). base is the length of the path without the tag.
+func (t *Tokenizer) element(tt TagType, name string, base int) (Token, error) {
+	tok := Token{Type: tt, Name: name}
+	t.pathKeep = base
+
+	var err error
+	switch tt {
+	case TAG_Compound:
+		if err = t.enter(); err == nil {
+			t.stack = append(t.stack, tokFrame{base: base})
+			t.pathKeep = len(t.path)
+			tok.Kind = TokenCompoundStart
+		}
+	case TAG_List:
+		if err = t.enter(); err != nil {
+			break
+		}
+		if tok.ElemType, tok.Len, err = t.readListHeader(); err != nil {
+			break
+		}
+		tok.Kind = TokenListStart
+		t.stack = append(t.stack, tokFrame{list: true, elemType: tok.ElemType, left: tok.Len, base: base})
+		t.pathKeep = len(t.path)
+	case TAG_Byte_Array, TAG_Int_Array, TAG_Long_Array:
+		if tok.Len, err = t.readLength(arrayName(tt)); err == nil {
+			tok.Kind = TokenArrayStart
+			t.array, t.arrayLen = tt, tok.Len
+		}
+	default:
+		tok.Kind = TokenValue
+		tok.Value, err = t.readValue(tt)
+	}
+
+	if err != nil {
+		return Token{}, t.fail(tt, err)
+	}
+	return tok, nil
+}
+
+func arrayName(tt TagType) string {
+	switch tt {
+	case TAG_Byte_Array:
+		return "Byte array"
+	case TAG_Int_Array:
+		return "Int Array"
+	}
+	return "Long Array"
+}
+
+func (t *Tokenizer) readValue(tt TagType) (interface{}, error) {
+	switch tt {
+	case TAG_End:
+		return nil, nil
+	case TAG_Byte:
+		return t.readByte()
+	case TAG_Short:
+		return t.readInt16()
+	case TAG_Int:
+		return t.readInt()
+	case TAG_Long:
+		return t.readLong()
+	case TAG_Float:
+		v, err := t.readInt32()
+		return math.Float32frombits(uint32(v)), err
+	case TAG_Double:
+		v, err := t.readInt64()
+		return math.Float64frombits(uint64(v)), err
+	case TAG_String:
+		return t.readString(true)
+	}
+	return nil, errors.New("Unknown tag type")
+}
+
+func (t *Tokenizer) arrayError(tt TagType) error {
+	if t.array != tt {
+		return fmt.Errorf("nbt: the last token is not the start of a %s", tt)
+	}
+	return nil
+}
+
+// ReadBytes reads up to len(p) elements of the TAG_Byte_Array started by the last token into p. It returns the number
+// of elements read and io.EOF, if all elements were read before.
+func (t *Tokenizer) ReadBytes(p []byte) (int, error) {
+	if err := t.arrayError(TAG_Byte_Array); err != nil {
+		return 0, err
+	}
+	if t.arrayLen == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	if len(p) > t.arrayLen {
+		p = p[:t.arrayLen]
+	}
+	if err := t.read(p); err != nil {
+		return 0, t.fail(t.array, err)
+	}
+	t.arrayLen -= len(p)
+	return len(p), nil
+}
+
+// ReadInts is like ReadBytes for a TAG_Int_Array.
+func (t *Tokenizer) ReadInts(p []int32) (int, error) {
+	if err := t.arrayError(TAG_Int_Array); err != nil {
+		return 0, err
+	}
+	if t.arrayLen == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	if len(p) > t.arrayLen {
+		p = p[:t.arrayLen]
+	}
+	for i := range p {
+		v, err := t.readInt()
+		if err != nil {
+			return i, t.fail(t.array, err)
+		}
+		p[i] = v
+		t.arrayLen--
+	}
+	return len(p), nil
+}
+
+// ReadLongs is like ReadBytes for a TAG_Long_Array.
+func (t *Tokenizer) ReadLongs(p []int64) (int, error) {
+	if err := t.arrayError(TAG_Long_Array); err != nil {
+		return 0, err
+	}
+	if t.arrayLen == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	if len(p) > t.arrayLen {
+		p = p[:t.arrayLen]
+	}
+	for i := range p {
+		v, err := t.readLong()
+		if err != nil {
+			return i, t.fail(t.array, err)
+		}
+		p[i] = v
+		t.arrayLen--
+	}
+	return len(p), nil
+}
+
+// readArray reads all remaining elements of the current array. Like readBytes, memory grows with the data read.
+func (t *Tokenizer) readArray() (interface{}, error) {
+	tt, l := t.array, t.arrayLen
+	t.arrayLen = 0
+
+	switch tt {
+	case TAG_Byte_Array:
+		data, err := t.readBytes(l)
+		if err != nil {
+			return nil, t.fail(tt, err)
+		}
+		return data, nil
+	case TAG_Int_Array:
+		data := make([]int32, 0, initialCap(l))
+		for i := 0; i < l; i++ {
+			v, err := t.readInt()
+			if err != nil {
+				return nil, t.fail(tt, err)
+			}
+			data = append(data, v)
+		}
+		return data, nil
+	case TAG_Long_Array:
+		data := make([]int64, 0, initialCap(l))
+		for i := 0; i < l; i++ {
+			v, err := t.readLong()
+			if err != nil {
+				return nil, t.fail(tt, err)
+			}
+			data = append(data, v)
+		}
+		return data, nil
+	}
+	return nil, t.arrayError(TAG_Byte_Array)
+}
+
+// skipArray skips the unread elements of the current array.
+func (t *Tokenizer) skipArray() error {
+	tt, l := t.array, t.arrayLen
+	t.array, t.arrayLen = TAG_End, 0
+	if l == 0 {
+		return nil
+	}
+
+	var err error
+	switch {
+	case tt == TAG_Byte_Array:
+		err = t.skip(int64(l))
+	case !t.network && tt == TAG_Int_Array:
+		err = t.skip(4 * int64(l))
+	case !t.network:
+		err = t.skip(8 * int64(l))
+	default:
+		for i := 0; i < l && err == nil; i++ {
+			if tt == TAG_Int_Array {
+				_, err = t.readInt()
+			} else {
+				_, err = t.readLong()
+			}
+		}
+	}
+	if err != nil {
+		return t.fail(tt, err)
+	}
+	return nil
+}
+
+// Skip skips the rest of the innermost open compound or list, including its end token. After a TokenCompoundStart or
+// TokenListStart, it skips that tag. The skipped data is not decoded.
+func (t *Tokenizer) Skip() error {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	if err := t.skipArray(); err != nil {
+		t.abort()
+		return err
+	}
+	t.path = t.path[:t.pathKeep]
+
+	f := &t.stack[len(t.stack)-1]
+	tt := TagType(TAG_Compound)
+	var err error
+	if f.list {
+		tt = TAG_List
+		err = t.skipElems(f.elemType, f.left, len(t.stack))
+		f.left = 0
+	} else {
+		err = t.skipEntries(len(t.stack))
+	}
+	if err != nil {
+		err = t.fail(tt, err)
+		t.abort()
+		return err
+	}
+
+	t.end()
+	return nil
+}
+
+// skipPayload skips the payload of a tag of type tt without decoding it. depth is the number of open compounds and lists.
+func (t *Tokenizer) skipPayload(tt TagType, depth int) error {
+	switch tt {
+	case TAG_End:
+		return nil
+	case TAG_Int:
+		if t.network {
+			_, err := t.readInt()
+			return err
+		}
+	case TAG_Long:
+		if t.network {
+			_, err := t.readLong()
+			return err
+		}
+	case TAG_String:
+		l, err := t.readStringLength()
+		if err != nil {
+			return err
+		}
+		return t.skip(int64(l))
+	case TAG_Byte_Array, TAG_Int_Array, TAG_Long_Array:
+		l, err := t.readLength(arrayName(tt))
+		if err != nil {
+			return err
+		}
+		return t.skipElems(elemTypeOf(tt), l, depth)
+	case TAG_List:
+		if err := t.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		ltt, l, err := t.readListHeader()
+		if err != nil {
+			return err
+		}
+		return t.skipElems(ltt, l, depth+1)
+	case TAG_Compound:
+		if err := t.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		return t.skipEntries(depth + 1)
+	}
+
+	if size := fixedSize(tt); size > 0 {
+		return t.skip(int64(size))
+	}
+	return errors.New("Unknown tag type")
+}
+
+// fixedSize returns the size of the payload of tt, if it does not depend on the data, and 0 otherwise.
+func fixedSize(tt TagType) int {
+	switch tt {
+	case TAG_Byte:
+		return 1
+	case TAG_Short:
+		return 2
+	case TAG_Int, TAG_Float:
+		return 4
+	case TAG_Long, TAG_Double:
+		return 8
+	}
+	return 0
+}
+
+// elemTypeOf returns the type of the elements of an array type.
+func elemTypeOf(tt TagType) TagType {
+	switch tt {
+	case TAG_Byte_Array:
+		return TAG_Byte
+	case TAG_Int_Array:
+		return TAG_Int
+	}
+	return TAG_Long
+}
+
+// skipElems skips n elements of type tt of a list or array.
+func (t *Tokenizer) skipElems(tt TagType, n, depth int) error {
+	if size := fixedSize(tt); size > 0 && !(t.network && (tt == TAG_Int || tt == TAG_Long)) {
+		return t.skip(int64(size) * int64(n))
+	}
+	for i := 0; i < n; i++ {
+		if err := t.skipPayload(tt, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipEntries skips the remaining entries of a compound, including its end.
+func (t *Tokenizer) skipEntries(depth int) error {
+	for {
+		tt, err := t.readByte()
+		if err != nil || tt == TAG_End {
+			return err
+		}
+		l, err := t.readStringLength()
+		if err != nil {
+			return err
+		}
+		if err := t.skip(int64(l)); err != nil {
+			return err
+		}
+		if err := t.skipPayload(TagType(tt), depth); err != nil {
+			return err
+		}
+	}
+}
+
+// readRawHeader reads the type and the undecoded name of a named tag. The name is only valid until the next read.
+func (t *Tokenizer) readRawHeader() (TagType, []byte, error) {
+	_tt, err := t.readByte()
+	if err != nil || _tt == TAG_End {
+		return TagType(_tt), nil, err
+	}
+
+	l, err := t.readStringLength()
+	if err != nil {
+		return TagType(_tt), nil, err
+	}
+	if cap(t.scratch) < l || cap(t.scratch) < allocChunk {
+		t.scratch = make([]byte, l+allocChunk)
+	}
+	name := t.scratch[:l]
+	return TagType(_tt), name, t.read(name)
+}
+
+// rawNameEquals checks, if the undecoded name raw equals name, without allocating.
+func (t *Tokenizer) rawNameEquals(raw []byte, name string) bool {
+	if t.strings == UTF8 || (bytes.IndexByte(raw, 0xc0) < 0 && bytes.IndexByte(raw, 0xed) < 0) {
+		// Modified UTF-8 only differs from UTF-8 in encoding NUL (0xc0 0x80) and supplementary characters (as surrogates starting with 0xed).
+		return string(raw) == name
+	}
+	s, err := decodeMUTF8(raw)
+	return err == nil && s == name
+}
+
+// fail wraps err into a *DecodeError for a tag of type tt at the current position. io.EOF before a root tag and errors
+// that are already wrapped are returned unchanged.
+func (t *Tokenizer) fail(tt TagType, err error) error {
+	if _, ok := err.(*DecodeError); ok || err == io.EOF {
+		return err
+	}
+	return &DecodeError{Offset: t.off, Path: t.path.String(), Type: tt, Err: err}
+}
+
+func (t *Tokenizer) read(p []byte) error {
+	if t.limits.MaxBytes > 0 && t.n+int64(len(p)) > t.limits.MaxBytes {
+		return &LimitError{"MaxBytes", t.limits.MaxBytes, t.n + int64(len(p))}
+	}
+
+	var n int
+	var err error
+	if t.fromBytes {
+		n = copy(p, t.in)
+		t.in = t.in[n:]
+		if n < len(p) {
+			err = io.ErrUnexpectedEOF
+			if n == 0 {
+				err = io.EOF
+			}
+		}
+	} else {
+		n, err = io.ReadFull(t.r, p)
+	}
+	t.n += int64(n)
+	t.off += int64(n)
+	if t.recording {
+		t.rec = append(t.rec, p[:n]...)
+	}
+	if err == io.EOF && t.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// skip discards l bytes.
+func (t *Tokenizer) skip(l int64) error {
+	if t.limits.MaxBytes > 0 && t.n+l > t.limits.MaxBytes {
+		return &LimitError{"MaxBytes", t.limits.MaxBytes, t.n + l}
+	}
+
+	if t.fromBytes && !t.recording {
+		n := int64(len(t.in))
+		if n > l {
+			n = l
+		}
+		t.in = t.in[n:]
+		t.n += n
+		t.off += n
+		if n < l {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+
+	if dr, ok := t.r.(interface{ Discard(int) (int, error) }); ok && !t.recording {
+		// A bufio.Reader discards without copying.
+		for l > 0 {
+			k := allocChunk * 16
+			if int64(k) > l {
+				k = int(l)
+			}
+			n, err := dr.Discard(k)
+			t.n += int64(n)
+			t.off += int64(n)
+			l -= int64(n)
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if cap(t.scratch) < allocChunk {
+		t.scratch = make([]byte, allocChunk)
+	}
+	for l > 0 {
+		p := t.scratch[:cap(t.scratch)]
+		if int64(len(p)) > l {
+			p = p[:l]
+		}
+		if err := t.read(p); err != nil {
+			return err
+		}
+		l -= int64(len(p))
+	}
+	return nil
+}
+
+// take reads l (at most 8) bytes. The result is only valid until the next read.
+func (t *Tokenizer) take(l int) ([]byte, error) {
+	if t.fromBytes && len(t.in) >= l && !t.recording && (t.limits.MaxBytes == 0 || t.n+int64(l) <= t.limits.MaxBytes) {
+		b := t.in[:l]
+		t.in = t.in[l:]
+		t.n += int64(l)
+		t.off += int64(l)
+		return b, nil
+	}
+
+	err := t.read(t.buf[:l])
+	return t.buf[:l], err
+}
+
+func (t *Tokenizer) readByte() (byte, error) {
+	b, err := t.take(1)
+	return b[0], err
+}
+
+func (t *Tokenizer) readInt16() (int16, error) {
+	b, err := t.take(2)
+	return int16(t.order.Uint16(b)), err
+}
+
+func (t *Tokenizer) readInt32() (int32, error) {
+	b, err := t.take(4)
+	return int32(t.order.Uint32(b)), err
+}
+
+func (t *Tokenizer) readInt64() (int64, error) {
+	b, err := t.take(8)
+	return int64(t.order.Uint64(b)), err
+}
+
+func (t *Tokenizer) readUvarint(maxBytes int) (uint64, error) {
+	var v uint64
+	for i := 0; i < maxBytes; i++ {
+		b, err := t.readByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << uint(7*i)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("Varint is too long")
+}
+
+// readInt reads the value of a TAG_Int.
+func (t *Tokenizer) readInt() (int32, error) {
+	if !t.network {
+		return t.readInt32()
+	}
+	u, err := t.readUvarint(5)
+	if u > math.MaxUint32 {
+		return 0, errors.New("Varint overflows 32 bits")
+	}
+	return int32(uint32(u)>>1) ^ -int32(u&1), err
+}
+
+// readLong reads the value of a TAG_Long.
+func (t *Tokenizer) readLong() (int64, error) {
+	if !t.network {
+		return t.readInt64()
+	}
+	u, err := t.readUvarint(10)
+	return int64(u>>1) ^ -int64(u&1), err
+}
+
+// readLength reads the length of an array or list.
+func (t *Tokenizer) readLength(what string) (int, error) {
+	l, err := t.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if l < 0 {
+		return 0, errors.New(what + " has negative length?")
+	}
+	if t.limits.MaxElems > 0 && int(l) > t.limits.MaxElems {
+		return 0, &LimitError{"MaxElems", int64(t.limits.MaxElems), int64(l)}
+	}
+	return int(l), nil
+}
+
+// readListHeader reads the element type and length of a list. A list of TAG_End must be empty, otherwise its elements
+// would take no space, so a short input could declare any number of them.
+func (t *Tokenizer) readListHeader() (TagType, int, error) {
+	ltt, err := t.readByte()
+	if err != nil {
+		return TAG_End, 0, err
+	}
+	l, err := t.readLength("List")
+	if err != nil {
+		return TAG_End, 0, err
+	}
+	if TagType(ltt) == TAG_End && l > 0 {
+		return TAG_End, 0, errors.New("Missing type of non-empty list")
+	}
+	return TagType(ltt), l, nil
+}
+
+// readStringLength reads the length of a string.
+func (t *Tokenizer) readStringLength() (int, error) {
+	l, err := t.readRawStringLength()
+	if err == nil && t.limits.MaxStringLen > 0 && l > t.limits.MaxStringLen {
+		return 0, &LimitError{"MaxStringLen", int64(t.limits.MaxStringLen), int64(l)}
+	}
+	return l, err
+}
+
+// readRawStringLength reads the length of a string. The network format stores it as a varint, so only the fixed-width
+// length of the other formats is limited to 16 bits.
+func (t *Tokenizer) readRawStringLength() (int, error) {
+	if t.network {
+		l, err := t.readUvarint(5)
+		if l > math.MaxInt32 {
+			return 0, errors.New("String is too long")
+		}
+		return int(l), err
+	}
+
+	l, err := t.readInt16()
+	if err != nil {
+		return 0, err
+	}
+	if l < 0 {
+		return 0, errors.New("String has negative length?")
+	}
+	return int(l), nil
+}
+
+// readBytes reads l bytes. The buffer grows with the data read, so a bogus length can't cause a huge allocation.
+// When reading from a byte slice with aliasing, the result shares memory with the input.
+func (t *Tokenizer) readBytes(l int) ([]byte, error) {
+	if t.fromBytes && !t.recording {
+		data, err := t.view(l)
+		if err != nil || t.alias {
+			return data, err
+		}
+		return append([]byte(nil), data...), nil
+	}
+
+	if t.limits.MaxBytes > 0 && t.n+int64(l) > t.limits.MaxBytes {
+		return nil, &LimitError{"MaxBytes", t.limits.MaxBytes, t.n + int64(l)}
+	}
+	if l <= allocChunk {
+		data := make([]byte, l)
+		return data, t.read(data)
+	}
+
+	var data []byte
+	for len(data) < l {
+		// Grow by at most the amount read so far (plus a chunk), so memory use stays proportional to the input.
+		n := l - len(data)
+		if step := len(data) + allocChunk; n > step {
+			n = step
+		}
+		start := len(data)
+		data = append(data, make([]byte, n)...)
+		if err := t.read(data[start:]); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// view reads l bytes. When reading from a byte slice, the result is part of the input and must not be modified.
+// Otherwise it is a new buffer (see readBytes).
+func (t *Tokenizer) view(l int) ([]byte, error) {
+	if !t.fromBytes || t.recording {
+		return t.readBytes(l)
+	}
+	if t.limits.MaxBytes > 0 && t.n+int64(l) > t.limits.MaxBytes {
+		return nil, &LimitError{"MaxBytes", t.limits.MaxBytes, t.n + int64(l)}
+	}
+	if l > len(t.in) {
+		return nil, t.skip(int64(l))
+	}
+	data := t.in[:l:l]
+	t.in = t.in[l:]
+	t.n += int64(l)
+	t.off += int64(l)
+	return data, nil
+}
+
+func (t *Tokenizer) decodeString(data []byte) (string, error) {
+	if t.strings == UTF8 {
+		if !utf8.Valid(data) {
+			return "", errInvalidUTF8
+		}
+		return string(data), nil
+	}
+	return decodeMUTF8(data)
+}
+
+// decodeInputString is like decodeString for data returned by view. With aliasing, the string shares memory with the
+// input, if the encoded form is valid UTF-8.
+func (t *Tokenizer) decodeInputString(data []byte) (string, error) {
+	if !t.alias || !t.fromBytes || t.recording || len(data) == 0 {
+		return t.decodeString(data)
+	}
+	if t.strings == UTF8 {
+		if !utf8.Valid(data) {
+			return "", errInvalidUTF8
+		}
+	} else if !isASCII(data) {
+		// Only ASCII is encoded the same in modified UTF-8 and UTF-8.
+		return decodeMUTF8(data)
+	}
+	return *(*string)(unsafe.Pointer(&data)), nil
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// enter checks the depth limit before a compound or list is opened.
+func (t *Tokenizer) enter() error {
+	return t.checkDepth(len(t.stack) + 1)
+}
+
+// checkDepth returns a *LimitError, if depth exceeds MaxDepth or, without a limit, DefaultMaxDepth.
+func (t *Tokenizer) checkDepth(depth int) error {
+	max := t.limits.MaxDepth
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	if depth > max {
+		return &LimitError{"MaxDepth", int64(max), int64(depth)}
+	}
+	return nil
+}
+
+// readTagHeader reads the type and name of a named tag. A TAG_End has no name.
+func (t *Tokenizer) readTagHeader() (TagType, string, error) {
+	_tt, err := t.readByte()
+	if err != nil {
+		return TAG_End, "", err
+	}
+	tt := TagType(_tt)
+	if tt == TAG_End {
+		return tt, "", nil
+	}
+
+	name, err := t.readString(false)
+	return tt, name, err
+}
+
+// readString reads a string. With alias, the string may share memory with the input (see decodeInputString). Names
+// never do, as they are used as map keys.
+func (t *Tokenizer) readString(alias bool) (string, error) {
+	l, err := t.readStringLength()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := t.view(l)
+	if err != nil {
+		return "", err
+	}
+	if !alias {
+		return t.decodeString(data)
+	}
+	return t.decodeInputString(data)
+}
@@ -0,0 +1,181 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func tokenizerTestData(t *testing.T, network bool) []byte {
+	tag := Tag{TAG_Compound, TagCompound{
+		"a":   NewByteTag(1),
+		"l":   NewListTag(TAG_Short, []int16{1, 2}),
+		"c":   Tag{TAG_Compound, TagCompound{"x": NewStringTag("y")}},
+		"arr": NewIntArrayTag([]int32{1, 2, 3}),
+		"e":   Tag{TAG_List, TagList{TAG_End, nil}},
+	}}
+	order := &KeyOrder{Names: []string{"a", "l", "c", "arr", "e"}}
+
+	buf := new(bytes.Buffer)
+	e := NewEncoder(buf)
+	if network {
+		e.UseNetworkFormat()
+	}
+	for _, name := range []string{"first", "second"} {
+		if err := e.EncodeOrdered(name, tag, order); err != nil {
+			t.Fatalf("Could not encode: %s", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestTokenizer(t *testing.T) {
+	want := []string{
+		`compound start TAG_Compound "first" at `,
+		`value TAG_Byte "a" at a: 1`,
+		`list start TAG_List "l" at l: TAG_Short[2]`,
+		`value TAG_Short "" at l[0]: 1`,
+		`value TAG_Short "" at l[1]: 2`,
+		`list end TAG_List "" at l`,
+		`compound start TAG_Compound "c" at c`,
+		`value TAG_String "x" at c.x: y`,
+		`compound end TAG_Compound "" at c`,
+		`array start TAG_Int_Array "arr" at arr: [3]`,
+		`list start TAG_List "e" at e: TAG_End[0]`,
+		`list end TAG_List "" at e`,
+		`compound end TAG_Compound "" at `,
+		`compound start TAG_Compound "second" at `,
+	}
+
+	for _, network := range []bool{false, true} {
+		tz := NewTokenizer(bytes.NewReader(tokenizerTestData(t, network)))
+		if network {
+			tz.UseNetworkFormat()
+		}
+		for i, w := range want {
+			tok, err := tz.Next()
+			if err != nil {
+				t.Fatalf("Token %d: %s", i, err)
+			}
+
+			have := fmt.Sprintf("%s %s %q at %s", tok.Kind, tok.Type, tok.Name, tz.Path())
+			switch tok.Kind {
+			case TokenValue:
+				have += fmt.Sprintf(": %v", tok.Value)
+			case TokenListStart:
+				have += fmt.Sprintf(": %s[%d]", tok.ElemType, tok.Len)
+			case TokenArrayStart:
+				have += fmt.Sprintf(": [%d]", tok.Len)
+			}
+			if have != w {
+				t.Errorf("Token %d: want %s, have %s", i, w, have)
+			}
+		}
+
+		// Skip the rest of the second root tag.
+		if err := tz.Skip(); err != nil {
+			t.Fatalf("Could not skip: %s", err)
+		}
+		if _, err := tz.Next(); err != io.EOF {
+			t.Errorf("Want io.EOF, have %v", err)
+		}
+	}
+}
+
+func TestTokenizerArrays(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	longs := []int64{1, -2, 3, 1 << 40, 5}
+	tag := Tag{TAG_Compound, TagCompound{
+		"bytes":   NewByteArrayTag(data),
+		"longs":   NewLongArrayTag(longs),
+		"skipped": NewLongArrayTag(longs),
+		"after":   NewIntTag(42),
+	}}
+	order := &KeyOrder{Names: []string{"bytes", "longs", "skipped", "after"}}
+
+	for _, network := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		e := NewEncoder(buf)
+		if network {
+			e.UseNetworkFormat()
+		}
+		e.EncodeOrdered("", tag, order)
+
+		tz := NewTokenizer(buf)
+		if network {
+			tz.UseNetworkFormat()
+		}
+		tz.Next()
+
+		// Read the byte array in chunks.
+		tok, _ := tz.Next()
+		if tok.Kind != TokenArrayStart || tok.Len != len(data) {
+			t.Fatalf("Wrong token %#v", tok)
+		}
+		var have []byte
+		chunk := make([]byte, 3000)
+		for {
+			n, err := tz.ReadBytes(chunk)
+			have = append(have, chunk[:n]...)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("ReadBytes failed: %s", err)
+			}
+		}
+		if !bytes.Equal(have, data) {
+			t.Errorf("Read wrong bytes")
+		}
+		if _, err := tz.ReadLongs(make([]int64, 1)); err == nil {
+			t.Errorf("Reading longs from a byte array should fail")
+		}
+
+		// Read only a part of the long arrays.
+		tz.Next()
+		part := make([]int64, 2)
+		if n, err := tz.ReadLongs(part); n != 2 || err != nil || part[0] != 1 || part[1] != -2 {
+			t.Errorf("ReadLongs: %d %v %v", n, part, err)
+		}
+		tz.Next()
+
+		tok, err := tz.Next()
+		if err != nil || tok.Name != "after" || tok.Value != int32(42) {
+			t.Errorf("Wrong token after arrays: %#v (err: %v)", tok, err)
+		}
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteNamedTag(buf, "", Tag{TAG_Compound, TagCompound{"bytes": NewByteArrayTag(make([]byte, 100))}})
+	data := buf.Bytes()
+
+	tz := NewTokenizer(bytes.NewReader(data[:50]))
+	tz.Next()
+	tz.Next()
+	_, err := tz.Next()
+	var de *DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, io.ErrUnexpectedEOF) || de.Path != "bytes" || de.Type != TAG_Byte_Array {
+		t.Errorf("Skipping truncated array: wrong error %v", err)
+	}
+
+	tz = NewTokenizer(bytes.NewReader(data))
+	tz.SetLimits(Limits{MaxDepth: 1})
+	tz.Next()
+	if err := tz.Skip(); err != nil {
+		t.Errorf("Skip failed: %s", err)
+	}
+
+	nested := []byte{TAG_List, 0, 0, TAG_List, 0, 0, 0, 1, TAG_Byte, 0, 0, 0, 0}
+	tz = NewTokenizer(bytes.NewReader(nested))
+	tz.SetLimits(Limits{MaxDepth: 1})
+	tz.Next()
+	if _, err := tz.Next(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Want ErrLimitExceeded, have %v", err)
+	}
+}
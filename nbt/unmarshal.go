@@ -0,0 +1,241 @@
+package nbt
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Unmarshal stores the data of tag in the value pointed to by v. It is the inverse of Marshal and uses the same mapping and field tags.
+//
+// Integer tags can be stored in any integer type that can hold the value; unsigned types of the same size as the tag get the same bits.
+// TAG_Float and TAG_Double can be stored in any float type, TAG_Byte in a bool. Lists and array tags can be stored in slices and arrays,
+// compounds in structs and map[string]T. Keys in a compound without a matching struct field are ignored.
+//
+// A Tag value receives the tag as is, an empty interface value receives the payload of the tag (see Tag for the payload types).
+func Unmarshal(tag Tag, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	return unmarshalValue("", tag, rv.Elem())
+}
+
+// InvalidUnmarshalError is returned by Unmarshal, if it was not given a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "nbt: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "nbt: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "nbt: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// UnmarshalTypeError is returned by Unmarshal, if a tag can not be stored in a Go value.
+type UnmarshalTypeError struct {
+	Path string // Path of the tag in the tag tree, e.g. "Inventory[2].Count"
+	Tag  TagType
+	Type reflect.Type
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("nbt: can not unmarshal %s into Go value of type %s at %s", e.Tag, e.Type, pathOrRoot(e.Path))
+}
+
+// intPayload returns the value of an integer tag.
+func intPayload(tag Tag) (int64, bool) {
+	switch tag.Type {
+	case TAG_Byte:
+		return int64(int8(tag.Payload.(byte))), true
+	case TAG_Short:
+		return int64(tag.Payload.(int16)), true
+	case TAG_Int:
+		return int64(tag.Payload.(int32)), true
+	case TAG_Long:
+		return tag.Payload.(int64), true
+	}
+	return 0, false
+}
+
+func intTagBits(tt TagType) int {
+	switch tt {
+	case TAG_Byte:
+		return 8
+	case TAG_Short:
+		return 16
+	case TAG_Int:
+		return 32
+	}
+	return 64
+}
+
+// sequence gives uniform access to the elements of list and array tags.
+type sequence struct {
+	elemType TagType
+	n        int
+	elem     func(i int) interface{}
+}
+
+func sequenceOf(tag Tag) (sequence, bool) {
+	switch tag.Type {
+	case TAG_List:
+		l := tag.Payload.(TagList)
+		return sequence{l.Type, len(l.Elems), func(i int) interface{} { return l.Elems[i] }}, true
+	case TAG_Byte_Array:
+		a := tag.Payload.([]byte)
+		return sequence{TAG_Byte, len(a), func(i int) interface{} { return a[i] }}, true
+	case TAG_Int_Array:
+		a := tag.Payload.([]int32)
+		return sequence{TAG_Int, len(a), func(i int) interface{} { return a[i] }}, true
+	case TAG_Long_Array:
+		a := tag.Payload.([]int64)
+		return sequence{TAG_Long, len(a), func(i int) interface{} { return a[i] }}, true
+	}
+	return sequence{}, false
+}
+
+func unmarshalValue(path string, tag Tag, v reflect.Value) error {
+	switch v.Type() {
+	case tagType:
+		v.Set(reflect.ValueOf(tag))
+		return nil
+	case tagListType:
+		if tag.Type != TAG_List {
+			return &UnmarshalTypeError{path, tag.Type, v.Type()}
+		}
+		v.Set(reflect.ValueOf(tag.Payload))
+		return nil
+	}
+
+	mismatch := func() error { return &UnmarshalTypeError{path, tag.Type, v.Type()} }
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(path, tag, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return mismatch()
+		}
+		if tag.Payload == nil {
+			// A TAG_End has no payload.
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		v.Set(reflect.ValueOf(tag.Payload))
+		return nil
+	case reflect.Bool:
+		if tag.Type != TAG_Byte {
+			return mismatch()
+		}
+		v.SetBool(tag.Payload.(byte) != 0)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := intPayload(tag)
+		if !ok || v.OverflowInt(i) {
+			return mismatch()
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := intPayload(tag)
+		if !ok {
+			return mismatch()
+		}
+		u := uint64(i)
+		if bits := intTagBits(tag.Type); bits == v.Type().Bits() {
+			u &= 1<<uint(bits) - 1
+		} else if i < 0 || v.OverflowUint(u) {
+			return mismatch()
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch tag.Type {
+		case TAG_Float:
+			v.SetFloat(float64(tag.Payload.(float32)))
+		case TAG_Double:
+			v.SetFloat(tag.Payload.(float64))
+		default:
+			return mismatch()
+		}
+		return nil
+	case reflect.String:
+		if tag.Type != TAG_String {
+			return mismatch()
+		}
+		v.SetString(tag.Payload.(string))
+		return nil
+	case reflect.Slice:
+		if data, ok := tag.Payload.([]byte); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), data...))
+			return nil
+		}
+		seq, ok := sequenceOf(tag)
+		if !ok {
+			return mismatch()
+		}
+		s := reflect.MakeSlice(v.Type(), seq.n, seq.n)
+		for i := 0; i < seq.n; i++ {
+			if err := unmarshalValue(indexPath(path, i), Tag{seq.elemType, seq.elem(i)}, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		seq, ok := sequenceOf(tag)
+		if !ok || seq.n > v.Len() {
+			return mismatch()
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i >= seq.n {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+				continue
+			}
+			if err := unmarshalValue(indexPath(path, i), Tag{seq.elemType, seq.elem(i)}, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if tag.Type != TAG_Compound || v.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		et := v.Type().Elem()
+		for key, elem := range tag.Payload.(TagCompound) {
+			ev := reflect.New(et).Elem()
+			if err := unmarshalValue(joinPath(path, key), elem, ev); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), ev)
+		}
+		return nil
+	case reflect.Struct:
+		if tag.Type != TAG_Compound {
+			return mismatch()
+		}
+		comp := tag.Payload.(TagCompound)
+		for _, f := range structFields(v.Type()) {
+			elem, ok := comp[f.name]
+			if !ok {
+				continue
+			}
+			if err := unmarshalValue(joinPath(path, f.name), elem, v.FieldByIndex(f.index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return &UnsupportedTypeError{path, v.Type()}
+}
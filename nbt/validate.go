@@ -0,0 +1,113 @@
+package nbt
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ValidationError describes a Tag whose payload does not fit its type.
+type ValidationError struct {
+	Path string  // Path of the tag, relative to the validated (or written) root tag.
+	Type TagType // Type of the tag.
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("nbt: invalid %s at %s: %s", e.Type, pathOrRoot(e.Path), e.Msg)
+}
+
+// ValidationErrors is returned by Tag.Validate and lists all problems found.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", errs[0], len(errs)-1)
+}
+
+func payloadMismatch(path string, tt TagType, data interface{}) *ValidationError {
+	return &ValidationError{path, tt, fmt.Sprintf("payload has type %T", data)}
+}
+
+// Validate checks, that the payloads of t and all of its children have the types required by their tag types (see Tag).
+// It returns nil or ValidationErrors, listing every mismatch with its path.
+//
+// Encoders check the same while writing, but stop at the first error and write nothing.
+func (t Tag) Validate() error {
+	var errs ValidationErrors
+	validate(&errs, Path{}, t.Type, t.Payload)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validate(errs *ValidationErrors, path Path, tt TagType, data interface{}) {
+	ok := true
+	switch tt {
+	case TAG_End:
+		ok = data == nil
+	case TAG_Byte:
+		_, ok = data.(byte)
+	case TAG_Short:
+		_, ok = data.(int16)
+	case TAG_Int:
+		_, ok = data.(int32)
+	case TAG_Long:
+		_, ok = data.(int64)
+	case TAG_Float:
+		_, ok = data.(float32)
+	case TAG_Double:
+		_, ok = data.(float64)
+	case TAG_Byte_Array:
+		_, ok = data.([]byte)
+	case TAG_String:
+		_, ok = data.(string)
+	case TAG_Int_Array:
+		_, ok = data.([]int32)
+	case TAG_Long_Array:
+		_, ok = data.([]int64)
+	case TAG_List:
+		var l TagList
+		if l, ok = data.(TagList); !ok {
+			break
+		}
+		if l.Type == TAG_End && len(l.Elems) > 0 {
+			*errs = append(*errs, &ValidationError{path.String(), tt, "list of TAG_End has elements"})
+			return
+		}
+		for i, el := range l.Elems {
+			validate(errs, path.Index(i), l.Type, el)
+		}
+	case TAG_Compound:
+		switch comp := data.(type) {
+		case TagCompound:
+			names := make([]string, 0, len(comp))
+			for name := range comp {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				validateEntry(errs, path.Key(name), comp[name])
+			}
+		default:
+			ok = false
+		}
+	default:
+		*errs = append(*errs, &ValidationError{path.String(), tt, "unknown tag type"})
+		return
+	}
+
+	if !ok {
+		*errs = append(*errs, payloadMismatch(path.String(), tt, data))
+	}
+}
+
+func validateEntry(errs *ValidationErrors, path Path, tag Tag) {
+	if tag.Type == TAG_End {
+		*errs = append(*errs, &ValidationError{path.String(), tag.Type, "TAG_End can not be stored in a compound"})
+		return
+	}
+	validate(errs, path, tag.Type, tag.Payload)
+}
@@ -0,0 +1,80 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	big, _, err := ReadGzipdNamedTag(bytes.NewReader(bigtest()))
+	if err != nil {
+		t.Fatalf("Could not read bigtest: %s", err)
+	}
+	if err := big.Validate(); err != nil {
+		t.Errorf("bigtest should be valid, have %v", err)
+	}
+
+	tag := Tag{TAG_Compound, TagCompound{
+		"a": Tag{TAG_Int, int64(1)},
+		"b": Tag{TAG_List, TagList{TAG_Short, []interface{}{int16(1), "two", int16(3)}}},
+		"c": Tag{TAG_Compound, TagCompound{"end": Tag{Type: TAG_End}, "ok": NewByteTag(1)}},
+		"d": Tag{TAG_List, TagList{TAG_End, []interface{}{nil}}},
+		"e": Tag{TagType(42), nil},
+	}}
+	err = tag.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Want ValidationErrors, have %v", err)
+	}
+
+	want := []struct {
+		path string
+		tt   TagType
+	}{
+		{"a", TAG_Int},
+		{"b[1]", TAG_Short},
+		{"c.end", TAG_End},
+		{"d", TAG_List},
+		{"e", TagType(42)},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Want %d errors, have %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Path != w.path || errs[i].Type != w.tt {
+			t.Errorf("Error %d: want %s at %s, have %v", i, w.tt, w.path, errs[i])
+		}
+	}
+}
+
+func TestEncoderInvalidPayload(t *testing.T) {
+	tags := map[string]Tag{
+		"Level.x":    {TAG_Compound, TagCompound{"Level": {TAG_Compound, TagCompound{"x": {TAG_Short, 1}}}}},
+		"list[2]":    {TAG_Compound, TagCompound{"list": NewListTag(TAG_String, []interface{}{"a", "b", 3})}},
+		"(root)":     {TAG_Compound, map[string]Tag{}},
+		"nested.end": {TAG_Compound, TagCompound{"nested": {TAG_Compound, TagCompound{"end": {Type: TAG_End}}}}},
+	}
+	for path, tag := range tags {
+		err := WriteNamedTag(new(bytes.Buffer), "", tag)
+		var ve *ValidationError
+		if !errors.As(err, &ve) || pathOrRoot(ve.Path) != path {
+			t.Errorf("%s: Want ValidationError, have %v", path, err)
+		}
+	}
+}
+
+func TestNewListTagChecked(t *testing.T) {
+	if _, err := NewListTagChecked(TAG_Int, []int32{1, 2}); err != nil {
+		t.Errorf("Valid list: %s", err)
+	}
+	if _, err := NewListTagChecked(TAG_Int, []int64{1, 2}); err == nil {
+		t.Errorf("List with wrong element type should fail")
+	}
+	if _, err := NewListTagChecked(TAG_Int, int32(1)); err == nil {
+		t.Errorf("List from non-slice should fail")
+	}
+	if _, err := NewListTagChecked(TAG_Int, nil); err == nil {
+		t.Errorf("List from nil should fail")
+	}
+}
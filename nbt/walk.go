@@ -0,0 +1,152 @@
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SkipSubtree can be returned by the functions called by Walk and Transform to skip the children of the current tag.
+// It is not returned as an error.
+var SkipSubtree = errors.New("nbt: skip subtree")
+
+// DeleteTag can be returned by the function called by Transform to remove the current tag from its compound or list.
+var DeleteTag = errors.New("nbt: delete tag")
+
+// WalkFunc is called by Walk for a tag and its concrete path.
+type WalkFunc func(path Path, t Tag) error
+
+// Walk calls fn for tag and all compound entries and list elements within it, in pre-order (every tag before its
+// children). The entries of a TagCompound are visited sorted by name. Arrays are not descended into.
+//
+// If fn returns SkipSubtree, the children of the tag are skipped. Any other error stops the walk and is returned.
+func Walk(tag Tag, fn WalkFunc) error {
+	return WalkPrePost(tag, fn, nil)
+}
+
+// WalkPrePost is like Walk, but calls pre before and post after the children of a tag. Either can be nil.
+// If pre returns SkipSubtree, the children are skipped, but post is still called for the tag.
+func WalkPrePost(tag Tag, pre, post WalkFunc) error {
+	err := walk(Path{}, tag, pre, post)
+	if err == SkipSubtree {
+		return nil
+	}
+	return err
+}
+
+func walk(path Path, tag Tag, pre, post WalkFunc) error {
+	skip := false
+	if pre != nil {
+		if err := pre(path, tag); err == SkipSubtree {
+			skip = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !skip {
+		switch v := tag.Payload.(type) {
+		case TagList:
+			for i, e := range v.Elems {
+				if err := walk(path.Index(i), Tag{v.Type, e}, pre, post); err != nil {
+					return err
+				}
+			}
+		case TagCompound:
+			for _, name := range sortedNames(v) {
+				if err := walk(path.Key(name), v[name], pre, post); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if post != nil {
+		if err := post(path, tag); err != SkipSubtree {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedNames(comp TagCompound) []string {
+	names := make([]string, 0, len(comp))
+	for name := range comp {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TransformFunc is called by Transform for a tag and its concrete path. It returns the tag that replaces t.
+type TransformFunc func(path Path, t Tag) (Tag, error)
+
+// Transform walks tag like Walk and replaces every tag by the result of fn. The children of the replacement are
+// transformed afterwards, unless fn returns SkipSubtree. If fn returns DeleteTag, the tag is removed from its compound
+// or list. Any other error stops the transformation and is returned with the partially transformed tag.
+//
+// Compounds and lists are changed in place, use Clone to keep the original. The element type of a list can't be
+// changed, a replacement element of another type causes a *ModifyError.
+func Transform(tag Tag, fn TransformFunc) (Tag, error) {
+	tag, err := transform(Path{}, tag, fn)
+	switch err {
+	case SkipSubtree:
+		return tag, nil
+	case DeleteTag:
+		return tag, &ModifyError{"", "can not remove the root tag"}
+	}
+	return tag, err
+}
+
+// transform transforms tag and its children. It returns DeleteTag or SkipSubtree as they are returned by fn.
+func transform(path Path, tag Tag, fn TransformFunc) (Tag, error) {
+	tag, err := fn(path, tag)
+	if err != nil {
+		return tag, err
+	}
+
+	// On errors, the partially transformed child is kept, or the original one, if the replacement is invalid.
+	switch v := tag.Payload.(type) {
+	case TagList:
+		elems := v.Elems[:0]
+		for i, e := range v.Elems {
+			child, err := transform(path.Index(i), Tag{v.Type, e}, fn)
+			if err == DeleteTag {
+				continue
+			}
+			if child.Type != v.Type {
+				if err == nil || err == SkipSubtree {
+					err = &ModifyError{path.Index(i).String(), fmt.Sprintf("%s does not match element type %s", child.Type, v.Type)}
+				}
+				child.Payload = e
+			}
+			elems = append(elems, child.Payload)
+			if err != nil && err != SkipSubtree {
+				tag.Payload = TagList{v.Type, append(elems, v.Elems[i+1:]...)}
+				return tag, err
+			}
+		}
+		if v.Elems != nil {
+			tag.Payload = TagList{v.Type, elems}
+		}
+	case TagCompound:
+		for _, name := range sortedNames(v) {
+			child, err := transform(path.Key(name), v[name], fn)
+			if err == DeleteTag {
+				delete(v, name)
+				continue
+			}
+			if child.Type == TAG_End {
+				if err == nil || err == SkipSubtree {
+					err = &ModifyError{path.Key(name).String(), "TAG_End can not be stored in a compound"}
+				}
+				child = v[name]
+			}
+			v[name] = child
+			if err != nil && err != SkipSubtree {
+				return tag, err
+			}
+		}
+	}
+	return tag, nil
+}
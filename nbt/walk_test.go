@@ -0,0 +1,127 @@
+package nbt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func walkTestTag(t *testing.T) Tag {
+	return mustSNBT(t, `{entities: [{id: "pig", pos: [1d, 2d]}, {id: "cow", passengers: [{id: "pig"}]}], data: [I; 1, 2], name: "x"}`)
+}
+
+func TestWalk(t *testing.T) {
+	var pre, post []string
+	err := WalkPrePost(walkTestTag(t), func(path Path, tag Tag) error {
+		pre = append(pre, path.String())
+		if path.String() == "entities[0].pos" {
+			return SkipSubtree
+		}
+		return nil
+	}, func(path Path, tag Tag) error {
+		post = append(post, path.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %s", err)
+	}
+
+	wantPre := []string{"", "data", "entities", "entities[0]", "entities[0].id", "entities[0].pos", "entities[1]",
+		"entities[1].id", "entities[1].passengers", "entities[1].passengers[0]", "entities[1].passengers[0].id", "name"}
+	if !reflect.DeepEqual(pre, wantPre) {
+		t.Errorf("Wrong pre-order:\nwant %q\nhave %q", wantPre, pre)
+	}
+	wantPost := []string{"data", "entities[0].id", "entities[0].pos", "entities[0]", "entities[1].id",
+		"entities[1].passengers[0].id", "entities[1].passengers[0]", "entities[1].passengers", "entities[1]", "entities",
+		"name", ""}
+	if !reflect.DeepEqual(post, wantPost) {
+		t.Errorf("Wrong post-order:\nwant %q\nhave %q", wantPost, post)
+	}
+
+	// Find all ids.
+	var ids []string
+	Walk(walkTestTag(t), func(path Path, tag Tag) error {
+		if len(path) > 0 && path[len(path)-1].Name == "id" && tag.Type == TAG_String {
+			ids = append(ids, tag.Payload.(string))
+		}
+		return nil
+	})
+	if want := []string{"pig", "cow", "pig"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Want ids %q, have %q", want, ids)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = Walk(walkTestTag(t), func(path Path, tag Tag) error {
+		if n++; n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Errorf("Walk should stop after the error, have %v after %d calls", err, n)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tag, err := Transform(walkTestTag(t), func(path Path, tag Tag) (Tag, error) {
+		switch {
+		case path.String() == "name":
+			return Tag{}, DeleteTag
+		case tag.Type == TAG_Compound && len(path) > 0:
+			if id, _ := compoundEntry(tag, "id"); id.Payload == "cow" {
+				return Tag{}, DeleteTag
+			}
+		case tag.Type == TAG_String:
+			return NewStringTag("minecraft:" + tag.Payload.(string)), nil
+		case tag.Type == TAG_Int_Array:
+			return tag, SkipSubtree
+		}
+		return tag, nil
+	})
+	if err != nil {
+		t.Fatalf("Transform failed: %s", err)
+	}
+	if want := mustSNBT(t, `{entities: [{id: "minecraft:pig", pos: [1d, 2d]}], data: [I; 1, 2]}`); !Equal(tag, want) {
+		t.Errorf("Wrong result %s", FormatSNBT(tag, SNBTOptions{}))
+	}
+
+	errorTests := map[string]TransformFunc{
+		"(root)": func(path Path, tag Tag) (Tag, error) { return tag, DeleteTag },
+		"entities[1]": func(path Path, tag Tag) (Tag, error) {
+			if path.String() == "entities[1]" {
+				return NewIntTag(1), nil
+			}
+			return tag, nil
+		},
+		"name": func(path Path, tag Tag) (Tag, error) {
+			if path.String() == "name" {
+				return Tag{}, nil
+			}
+			return tag, nil
+		},
+	}
+	for path, fn := range errorTests {
+		_, err := Transform(walkTestTag(t), fn)
+		var me *ModifyError
+		if !errors.As(err, &me) || pathOrRoot(me.Path) != path {
+			t.Errorf("%s: Want ModifyError, have %v", path, err)
+		}
+	}
+
+	// Elements after an error are kept.
+	stop := errors.New("stop")
+	tag = walkTestTag(t)
+	tag, err = Transform(tag, func(path Path, tag Tag) (Tag, error) {
+		switch path.String() {
+		case "entities[0]":
+			return Tag{}, DeleteTag
+		case "entities[1]":
+			return tag, stop
+		}
+		return tag, nil
+	})
+	if entities, _ := compoundEntry(tag, "entities"); err != stop || elemCount(entities) != 1 {
+		t.Errorf("Want error stop and 1 entity, have %v, %s", err, FormatSNBT(tag, SNBTOptions{}))
+	}
+}